@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+
+	"github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Annotation on v1alpha1 CatFacts holding the v1beta1 spec and status, so
+// fields v1alpha1 doesn't have survive a round trip through v1alpha1
+const ConversionDataAnnotation = "catfacts.ryanmillerc.github.io/conversion-data"
+
+// v1beta1 fields saved in ConversionDataAnnotation
+type conversionData struct {
+	Spec   v1beta1.CatFactSpec   `json:"spec,omitempty"`
+	Status v1beta1.CatFactStatus `json:"status,omitempty"`
+}
+
+// ConvertTo converts this CatFact to the hub version, v1beta1.
+//
+// A v1alpha1 spec.fact and spec.iconName may have been resolved from
+// v1beta1's status, see ConvertFrom. If they weren't changed since, they're
+// dropped from the v1beta1 spec again.
+func (src *CatFact) ConvertTo(dstRaw conversion.Hub) error {
+	dst := dstRaw.(*v1beta1.CatFact)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = v1beta1.CatFactSpec{
+		Fact:     src.Spec.Fact,
+		IconName: src.Spec.IconName,
+	}
+	dst.Status = v1beta1.CatFactStatus{}
+
+	raw, ok := dst.Annotations[ConversionDataAnnotation]
+	if !ok {
+		return nil
+	}
+	delete(dst.Annotations, ConversionDataAnnotation)
+	if len(dst.Annotations) == 0 {
+		dst.Annotations = nil
+	}
+
+	var saved conversionData
+	if err := json.Unmarshal([]byte(raw), &saved); err != nil {
+		return fmt.Errorf("invalid %s annotation: %w", ConversionDataAnnotation, err)
+	}
+	dst.Status = saved.Status
+	dst.Spec = saved.Spec
+	dst.Spec.Fact = src.Spec.Fact
+	dst.Spec.IconName = src.Spec.IconName
+	if src.Spec.Fact == saved.Status.Fact {
+		dst.Spec.Fact = saved.Spec.Fact
+	}
+	if saved.Spec.IconName == "" && src.Spec.IconName == saved.Status.IconName {
+		dst.Spec.IconName = ""
+	}
+	return nil
+}
+
+// ConvertFrom converts from the hub version, v1beta1, to this version.
+//
+// v1alpha1 doesn't have a status, so the resolved fact and icon are shown in
+// spec when v1beta1's spec omits them. A spec.fact translated into
+// spec.locale is shown translated. Fields v1alpha1 doesn't have are saved in
+// ConversionDataAnnotation.
+func (dst *CatFact) ConvertFrom(srcRaw conversion.Hub) error {
+	src := srcRaw.(*v1beta1.CatFact)
+	dst.ObjectMeta = *src.ObjectMeta.DeepCopy()
+	dst.Spec = CatFactSpec{
+		Fact:     src.Spec.Fact,
+		IconName: src.Spec.IconName,
+	}
+	if dst.Spec.Fact == "" || (src.Status.Locale != "" && src.Status.OriginalFact == src.Spec.Fact) {
+		dst.Spec.Fact = src.Status.Fact
+	}
+	if dst.Spec.IconName == "" {
+		dst.Spec.IconName = src.Status.IconName
+	}
+
+	extra := src.Spec
+	extra.Fact, extra.IconName = "", ""
+	if reflect.DeepEqual(extra, v1beta1.CatFactSpec{}) && reflect.DeepEqual(src.Status, v1beta1.CatFactStatus{}) {
+		return nil
+	}
+	raw, err := json.Marshal(conversionData{Spec: src.Spec, Status: src.Status})
+	if err != nil {
+		return err
+	}
+	if dst.Annotations == nil {
+		dst.Annotations = map[string]string{}
+	}
+	dst.Annotations[ConversionDataAnnotation] = string(raw)
+	return nil
+}
@@ -0,0 +1,136 @@
+package v1alpha1
+
+import (
+	"math/rand"
+	"testing"
+
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/randfill"
+
+	"github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func newFiller(t *testing.T) *randfill.Filler {
+	scheme := runtime.NewScheme()
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := v1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	seed := rand.Int63()
+	t.Logf("fuzzer seed %d", seed)
+	return fuzzer.FuzzerFor(metafuzzer.Funcs, rand.NewSource(seed), serializer.NewCodecFactory(scheme))
+}
+
+func TestFuzzyConversionSpokeHubSpoke(t *testing.T) {
+	filler := newFiller(t)
+	for i := 0; i < 1000; i++ {
+		original := &CatFact{}
+		filler.Fill(original)
+		delete(original.Annotations, ConversionDataAnnotation)
+
+		hub := &v1beta1.CatFact{}
+		if err := original.DeepCopy().ConvertTo(hub); err != nil {
+			t.Fatalf("ConvertTo: %v", err)
+		}
+		converted := &CatFact{}
+		if err := converted.ConvertFrom(hub); err != nil {
+			t.Fatalf("ConvertFrom: %v", err)
+		}
+		converted.TypeMeta = original.TypeMeta
+		if !apiequality.Semantic.DeepEqual(original, converted) {
+			t.Fatalf("v1alpha1 -> v1beta1 -> v1alpha1 changed the CatFact:\n%+v\n%+v", original, converted)
+		}
+	}
+}
+
+func TestFuzzyConversionHubSpokeHub(t *testing.T) {
+	filler := newFiller(t)
+	for i := 0; i < 1000; i++ {
+		original := &v1beta1.CatFact{}
+		filler.Fill(original)
+		delete(original.Annotations, ConversionDataAnnotation)
+		// Exercise facts and icons that were resolved into status
+		if i%2 == 0 {
+			original.Spec.Fact = ""
+		}
+		if i%3 == 0 {
+			original.Spec.IconName = ""
+		}
+
+		spoke := &CatFact{}
+		if err := spoke.ConvertFrom(original.DeepCopy()); err != nil {
+			t.Fatalf("ConvertFrom: %v", err)
+		}
+		converted := &v1beta1.CatFact{}
+		if err := spoke.ConvertTo(converted); err != nil {
+			t.Fatalf("ConvertTo: %v", err)
+		}
+		converted.TypeMeta = original.TypeMeta
+		if !apiequality.Semantic.DeepEqual(original, converted) {
+			t.Fatalf("v1beta1 -> v1alpha1 -> v1beta1 changed the CatFact:\n%+v\n%+v", original, converted)
+		}
+	}
+}
+
+func TestConvertFromResolvedStatus(t *testing.T) {
+	hub := &v1beta1.CatFact{
+		Spec: v1beta1.CatFactSpec{Tags: []string{"history"}},
+		Status: v1beta1.CatFactStatus{
+			Fact:     "Cats are cool!",
+			IconName: "Joy",
+			Source:   "catfact.ninja",
+		},
+	}
+	spoke := &CatFact{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if spoke.Spec.Fact != "Cats are cool!" || spoke.Spec.IconName != "Joy" {
+		t.Errorf("Expected the resolved fact and icon in the v1alpha1 spec, got %+v", spoke.Spec)
+	}
+
+	// A v1alpha1 client changing the fact sets it in the v1beta1 spec
+	spoke.Spec.Fact = "Cats sleep a lot"
+	converted := &v1beta1.CatFact{}
+	if err := spoke.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Spec.Fact != "Cats sleep a lot" || converted.Spec.IconName != "" {
+		t.Errorf("Unexpected v1beta1 spec %+v", converted.Spec)
+	}
+	if len(converted.Spec.Tags) != 1 || converted.Annotations[ConversionDataAnnotation] != "" {
+		t.Errorf("Expected tags to be restored from the annotation, got %+v", converted)
+	}
+}
+
+func TestConvertFromTranslatedFact(t *testing.T) {
+	hub := &v1beta1.CatFact{
+		Spec: v1beta1.CatFactSpec{Fact: "Cats are cool!", Locale: "es"},
+		Status: v1beta1.CatFactStatus{
+			Fact:         "¡Los gatos son geniales!",
+			OriginalFact: "Cats are cool!",
+			Locale:       "es",
+		},
+	}
+	spoke := &CatFact{}
+	if err := spoke.ConvertFrom(hub); err != nil {
+		t.Fatal(err)
+	}
+	if spoke.Spec.Fact != "¡Los gatos son geniales!" {
+		t.Errorf("Expected the translated fact in the v1alpha1 spec, got %+v", spoke.Spec)
+	}
+
+	converted := &v1beta1.CatFact{}
+	if err := spoke.ConvertTo(converted); err != nil {
+		t.Fatal(err)
+	}
+	if converted.Spec.Fact != "Cats are cool!" {
+		t.Errorf("Expected the untranslated fact to stay in the v1beta1 spec, got %+v", converted.Spec)
+	}
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Label on CatFacts and ClusterCatFacts mirroring status.category, so they
+// can be selected by category
+const CategoryLabel = "catfacts.ryanmillerc.github.io/category"
+
+// Annotation on CatFacts and ClusterCatFacts naming a revision in
+// status.history to roll the fact and icon back to. The operator removes it
+// once the CatFact is rolled back.
+const RollbackAnnotation = "catfacts.ryanmillerc.github.io/rollback-to"
+
+// Fields CatFacts are indexed by in the operator's cache
+const (
+	// Indexes each of spec.tags
+	TagsField = "spec.tags"
+
+	// Indexes status.category
+	CategoryField = "status.category"
+)
+
+// CatFactSpec defines the desired state of CatFact
+type CatFactSpec struct {
+	// A fact about cats. If this field is omitted, a fact is generated from
+	// sourceRef and recorded in status.fact.
+	// +optional
+	Fact string `json:"fact,omitempty"`
+
+	// Icon to use when displayed in the OpenShift UI. See
+	// https://github.com/RyanMillerC/cat-facts-operator/README.md for available
+	// icon names. If this field is omitted, a random iconName is recorded in
+	// status.iconName.
+	// +optional
+	IconName string `json:"iconName,omitempty"`
+
+	// Fact source that generates the fact when spec.fact is omitted. Defaults
+	// to the first fact source in the operator config.
+	// +optional
+	SourceRef *FactSourceReference `json:"sourceRef,omitempty"`
+
+	// Tags describing the fact. CatFacts can be looked up by tag through the
+	// console plugin backend.
+	// +optional
+	// +listType=set
+	// +kubebuilder:validation:MaxItems=16
+	// +kubebuilder:validation:items:MaxLength=63
+	// +kubebuilder:validation:items:Pattern=`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`
+	Tags []string `json:"tags,omitempty"`
+
+	// When a generated fact is replaced with a new one. Facts set in
+	// spec.fact are never refreshed. If omitted, the first generated fact is
+	// kept.
+	// +optional
+	RefreshPolicy *RefreshPolicy `json:"refreshPolicy,omitempty"`
+
+	// Category of generated facts, if the fact source can serve facts by
+	// category. Applies to the next generated fact.
+	// +optional
+	// +kubebuilder:validation:Enum=anatomy;behavior;breeds;diet;health;history;senses;general
+	Category string `json:"category,omitempty"`
+
+	// BCP 47 language tag of the fact, e.g. "en" or "pt-BR". Facts are
+	// translated from English by the translators in the operator config; if
+	// none has a translation, the English fact is shown.
+	// +optional
+	// +kubebuilder:validation:MaxLength=35
+	// +kubebuilder:validation:Pattern=`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`
+	Locale string `json:"locale,omitempty"`
+
+	// Freeze the fact and icon. While locked, fact and iconName can't be
+	// changed and the operator doesn't refresh, translate, or replace the
+	// resolved fact and icon. Unlocking requires the "unlock" verb on
+	// catfacts.
+	// +optional
+	Locked bool `json:"locked,omitempty"`
+}
+
+// FactSourceReference refers to a fact source in the operator config
+type FactSourceReference struct {
+	// Name of an entry in the operator config's factSources
+	// +kubebuilder:validation:MinLength=1
+	Name string `json:"name"`
+}
+
+// RefreshPolicy controls when a generated fact is replaced
+type RefreshPolicy struct {
+	// Time between generating new facts, e.g. "24h". Must be at least 1m.
+	Interval metav1.Duration `json:"interval"`
+}
+
+// CatFactStatus defines the observed state of CatFact
+type CatFactStatus struct {
+	// The fact shown for this CatFact: spec.fact, or the generated fact,
+	// translated into spec.locale
+	// +optional
+	Fact string `json:"fact,omitempty"`
+
+	// The fact before it was translated. Empty unless status.fact is
+	// translated.
+	// +optional
+	OriginalFact string `json:"originalFact,omitempty"`
+
+	// Locale status.fact is translated into. Empty unless status.fact is
+	// translated.
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// The icon shown for this CatFact: spec.iconName, or the generated icon
+	// +optional
+	IconName string `json:"iconName,omitempty"`
+
+	// Name of the fact source that generated status.fact, or "placeholder"
+	// if it couldn't be reached. Empty when the fact comes from spec.fact.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Category of status.fact: the category it was requested in, or the one
+	// derived from its text by a keyword classifier
+	// +optional
+	Category string `json:"category,omitempty"`
+
+	// When status.fact was last generated
+	// +optional
+	LastRefreshTime *metav1.Time `json:"lastRefreshTime,omitempty"`
+
+	// How the generated fact was moderated. Empty when the fact comes from
+	// spec.fact or no moderators are configured.
+	// +optional
+	Moderation *ModerationStatus `json:"moderation,omitempty"`
+
+	// Failed attempts to translate status.fact into spec.locale. Translating
+	// is retried with exponential backoff. Empty once the fact is translated.
+	// +optional
+	TranslationFailure *TranslationFailure `json:"translationFailure,omitempty"`
+
+	// Sum of the CatFactVotes on this CatFact: upvotes minus downvotes.
+	// ClusterCatFacts aren't voted on.
+	// +optional
+	Rating int32 `json:"rating,omitempty"`
+
+	// Number of CatFactVotes on this CatFact
+	// +optional
+	Votes int32 `json:"votes,omitempty"`
+
+	// Revision of the fact and icon in status. Incremented whenever either
+	// changes.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+
+	// Facts and icons shown before the current revision, newest first. Only
+	// the latest revisions are kept.
+	// +optional
+	// +kubebuilder:validation:MaxItems=10
+	History []FactRevision `json:"history,omitempty"`
+
+	// The generation of the spec that status was resolved from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+// FactRevision is a fact and icon previously shown for a CatFact
+type FactRevision struct {
+	// Revision number, see status.revision
+	Revision int64 `json:"revision"`
+
+	// The fact shown, translated into locale if it's set
+	// +optional
+	Fact string `json:"fact,omitempty"`
+
+	// The fact before it was translated
+	// +optional
+	OriginalFact string `json:"originalFact,omitempty"`
+
+	// Locale fact is translated into
+	// +optional
+	Locale string `json:"locale,omitempty"`
+
+	// The icon shown
+	// +optional
+	IconName string `json:"iconName,omitempty"`
+
+	// Fact source that generated the fact. Empty if it came from spec.fact.
+	// +optional
+	Source string `json:"source,omitempty"`
+
+	// Category of the fact
+	// +optional
+	Category string `json:"category,omitempty"`
+
+	// When the revision was replaced
+	ReplacedTime metav1.Time `json:"replacedTime"`
+}
+
+// Moderation decisions
+const (
+	// The generated fact passed moderation
+	ModerationAllowed = "Allowed"
+
+	// Every fetched fact was rejected, so the placeholder fact is shown
+	ModerationReplaced = "Replaced"
+)
+
+// ModerationStatus records how a generated fact was moderated
+type ModerationStatus struct {
+	// Allowed or Replaced
+	// +kubebuilder:validation:Enum=Allowed;Replaced
+	Decision string `json:"decision"`
+
+	// Number of fetched facts rejected before status.fact was chosen
+	// +optional
+	Rejected int32 `json:"rejected,omitempty"`
+
+	// Moderator that rejected the last rejected fact
+	// +optional
+	Moderator string `json:"moderator,omitempty"`
+
+	// Why the last rejected fact was rejected
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+// TranslationFailure records failed attempts to translate a fact
+type TranslationFailure struct {
+	// Locale the fact couldn't be translated into
+	Locale string `json:"locale"`
+
+	// The untranslated fact
+	Fact string `json:"fact"`
+
+	// Consecutive failed attempts
+	Attempts int32 `json:"attempts"`
+
+	// When translating last failed
+	LastAttemptTime metav1.Time `json:"lastAttemptTime"`
+
+	// Why translating last failed
+	// +optional
+	Reason string `json:"reason,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:storageversion
+//+kubebuilder:printcolumn:name="Icon",type=string,JSONPath=`.status.iconName`
+//+kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.status.source`
+//+kubebuilder:printcolumn:name="Category",type=string,JSONPath=`.status.category`
+//+kubebuilder:printcolumn:name="Tags",type=string,JSONPath=`.spec.tags`,priority=1
+//+kubebuilder:printcolumn:name="Rating",type=integer,JSONPath=`.status.rating`,priority=1
+//+kubebuilder:printcolumn:name="Fact",type=string,JSONPath=`.status.fact`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CatFact is a Kubernetes model of a fact about cats 🐱
+type CatFact struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	//+kubebuilder:validation:XValidation:rule="!has(oldSelf.locked) || !oldSelf.locked || ((has(self.fact) ? self.fact : '') == (has(oldSelf.fact) ? oldSelf.fact : '') && (has(self.iconName) ? self.iconName : '') == (has(oldSelf.iconName) ? oldSelf.iconName : ''))",message="fact and iconName can't be changed while locked"
+	Spec   CatFactSpec   `json:"spec,omitempty"`
+	Status CatFactStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CatFactList contains a list of CatFact
+type CatFactList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CatFact `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CatFact{}, &CatFactList{})
+}
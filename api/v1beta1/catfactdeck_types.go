@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Label on CatFacts created from a CatFactDeck member template, set to the
+// deck's name
+const DeckLabel = "catfacts.ryanmillerc.github.io/deck"
+
+// CatFactDeckSpec defines the desired state of CatFactDeck
+type CatFactDeckSpec struct {
+	// CatFacts in the deck, in order
+	// +optional
+	// +listType=map
+	// +listMapKey=name
+	// +kubebuilder:validation:MaxItems=100
+	Members []DeckMember `json:"members,omitempty"`
+
+	// CatFacts matching this selector follow members, ordered by name
+	// +optional
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+
+	// Time between advancing status.current to the next member, e.g. "1h".
+	// Must be at least 1m. If omitted, status.current only changes when the
+	// members change.
+	// +optional
+	AdvanceInterval *metav1.Duration `json:"advanceInterval,omitempty"`
+}
+
+// DeckMember refers to a CatFact in the deck's namespace
+type DeckMember struct {
+	// Name of the CatFact
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	Name string `json:"name"`
+
+	// CatFact created if none named name exists. Created CatFacts are owned
+	// by the deck and deleted with it. Without a template, a missing CatFact
+	// is left out of the deck.
+	// +optional
+	Template *CatFactTemplate `json:"template,omitempty"`
+}
+
+// CatFactTemplate describes a CatFact created for a deck member
+type CatFactTemplate struct {
+	// Labels added to the CatFact
+	// +optional
+	Labels map[string]string `json:"labels,omitempty"`
+
+	// Spec of the CatFact
+	// +optional
+	Spec CatFactSpec `json:"spec,omitempty"`
+}
+
+// CatFactDeckStatus defines the observed state of CatFactDeck
+type CatFactDeckStatus struct {
+	// Names of the CatFacts in the deck, in order
+	// +optional
+	Members []string `json:"members,omitempty"`
+
+	// Number of CatFacts in the deck
+	// +optional
+	Count int32 `json:"count,omitempty"`
+
+	// Index in members of the CatFact currently shown
+	// +optional
+	Current int32 `json:"current,omitempty"`
+
+	// Name of the CatFact currently shown
+	// +optional
+	CurrentName string `json:"currentName,omitempty"`
+
+	// When current last advanced
+	// +optional
+	LastAdvanceTime *metav1.Time `json:"lastAdvanceTime,omitempty"`
+
+	// Members without a CatFact or a template to create one from
+	// +optional
+	MissingMembers []string `json:"missingMembers,omitempty"`
+
+	// The generation of the spec that status was resolved from
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:shortName=deck
+//+kubebuilder:printcolumn:name="Count",type=integer,JSONPath=`.status.count`
+//+kubebuilder:printcolumn:name="Current",type=string,JSONPath=`.status.currentName`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CatFactDeck is a named, ordered collection of CatFacts 🃏
+type CatFactDeck struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CatFactDeckSpec   `json:"spec,omitempty"`
+	Status CatFactDeckStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CatFactDeckList contains a list of CatFactDeck
+type CatFactDeckList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CatFactDeck `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CatFactDeck{}, &CatFactDeckList{})
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// CatFactObject is a CatFact or a ClusterCatFact. Both have a fact and icon
+// resolved the same way.
+// +kubebuilder:object:generate=false
+type CatFactObject interface {
+	client.Object
+
+	// Return the fields shared by CatFact and ClusterCatFact specs
+	GetCatFactSpec() *CatFactSpec
+
+	// Return the object's status
+	GetCatFactStatus() *CatFactStatus
+}
+
+var _ CatFactObject = &CatFact{}
+var _ CatFactObject = &ClusterCatFact{}
+
+func (c *CatFact) GetCatFactSpec() *CatFactSpec {
+	return &c.Spec
+}
+
+func (c *CatFact) GetCatFactStatus() *CatFactStatus {
+	return &c.Status
+}
+
+func (c *ClusterCatFact) GetCatFactSpec() *CatFactSpec {
+	return &c.Spec.CatFactSpec
+}
+
+func (c *ClusterCatFact) GetCatFactStatus() *CatFactStatus {
+	return &c.Status
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Field CatFactVotes are indexed by in the operator's cache. It's also a
+// selectable field of the CRD, so it can be used without the cache.
+const CatFactNameField = "spec.catFactName"
+
+// CatFactVoteSpec defines the desired state of CatFactVote
+type CatFactVoteSpec struct {
+	// Name of the CatFact voted on, in the vote's namespace
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:MaxLength=253
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="catFactName is immutable"
+	CatFactName string `json:"catFactName"`
+
+	// Username of the voter, as authenticated by the operator backend
+	// +kubebuilder:validation:MinLength=1
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="user is immutable"
+	User string `json:"user"`
+
+	// 1 for an upvote, -1 for a downvote
+	// +kubebuilder:validation:Enum=-1;1
+	Value int32 `json:"value"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:shortName=vote
+//+kubebuilder:selectablefield:JSONPath=`.spec.catFactName`
+//+kubebuilder:printcolumn:name="CatFact",type=string,JSONPath=`.spec.catFactName`
+//+kubebuilder:printcolumn:name="User",type=string,JSONPath=`.spec.user`
+//+kubebuilder:printcolumn:name="Value",type=integer,JSONPath=`.spec.value`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CatFactVote is one user's vote on a CatFact 👍
+type CatFactVote struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec CatFactVoteSpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CatFactVoteList contains a list of CatFactVote
+type CatFactVoteList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CatFactVote `json:"items"`
+}
+
+// Return the name of user's vote on the CatFact catFactName. Each user has at
+// most one vote per CatFact, so voting again replaces the vote.
+func VoteName(catFactName, user string) string {
+	sum := sha256.Sum256([]byte(catFactName + "/" + user))
+	return "vote-" + hex.EncodeToString(sum[:16])
+}
+
+func init() {
+	SchemeBuilder.Register(&CatFactVote{}, &CatFactVoteList{})
+}
@@ -0,0 +1,65 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1beta1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ClusterCatFactSpec defines the desired state of ClusterCatFact
+type ClusterCatFactSpec struct {
+	CatFactSpec `json:",inline"`
+
+	// Namespaces whose Cat Facts tab shows this fact, selected by their
+	// labels. If omitted, it's shown in every namespace.
+	// +optional
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:resource:scope=Cluster
+//+kubebuilder:printcolumn:name="Icon",type=string,JSONPath=`.status.iconName`
+//+kubebuilder:printcolumn:name="Source",type=string,JSONPath=`.status.source`
+//+kubebuilder:printcolumn:name="Category",type=string,JSONPath=`.status.category`
+//+kubebuilder:printcolumn:name="Tags",type=string,JSONPath=`.spec.tags`,priority=1
+//+kubebuilder:printcolumn:name="Fact",type=string,JSONPath=`.status.fact`,priority=1
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ClusterCatFact is a fact about cats shown in every namespace, or the
+// namespaces matching its namespaceSelector 🐱
+type ClusterCatFact struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	//+kubebuilder:validation:XValidation:rule="!has(oldSelf.locked) || !oldSelf.locked || ((has(self.fact) ? self.fact : '') == (has(oldSelf.fact) ? oldSelf.fact : '') && (has(self.iconName) ? self.iconName : '') == (has(oldSelf.iconName) ? oldSelf.iconName : ''))",message="fact and iconName can't be changed while locked"
+	Spec   ClusterCatFactSpec `json:"spec,omitempty"`
+	Status CatFactStatus      `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ClusterCatFactList contains a list of ClusterCatFact
+type ClusterCatFactList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ClusterCatFact `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ClusterCatFact{}, &ClusterCatFactList{})
+}
@@ -0,0 +1,42 @@
+import * as React from 'react';
+import { consoleFetchJSON } from '@openshift-console/dynamic-plugin-sdk';
+
+// The operator backend API, reached through the ConsolePlugin proxy with the
+// logged-in user's token
+export const BACKEND_URL = '/api/proxy/plugin/cat-facts-operator-console-plugin/backend/api/v1';
+
+export type RandomFact = {
+  fact: string;
+  iconName: string;
+  provider: string;
+};
+
+// Fetch a fact from the operator's first fact source
+export const fetchRandomFact = (): Promise<RandomFact> => consoleFetchJSON(`${BACKEND_URL}/facts/random`);
+
+// Return the icons CatFacts may use under the operator's icon policy, whether
+// they've loaded, and the load error, if any
+export const useIconOptions = (): [string[], boolean, unknown] => {
+  const [icons, setIcons] = React.useState<string[]>([]);
+  const [loaded, setLoaded] = React.useState(false);
+  const [loadError, setLoadError] = React.useState<unknown>();
+
+  React.useEffect(() => {
+    let cancelled = false;
+    consoleFetchJSON(`${BACKEND_URL}/icons`)
+      .then((response: { icons: string[] }) => {
+        if (!cancelled) setIcons(response.icons ?? []);
+      })
+      .catch((err) => {
+        if (!cancelled) setLoadError(err);
+      })
+      .finally(() => {
+        if (!cancelled) setLoaded(true);
+      });
+    return () => {
+      cancelled = true;
+    };
+  }, []);
+
+  return [icons, loaded, loadError];
+};
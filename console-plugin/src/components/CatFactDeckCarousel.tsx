@@ -0,0 +1,96 @@
+import * as React from 'react';
+import { ResourceLink, useK8sWatchResource } from '@openshift-console/dynamic-plugin-sdk';
+import {
+  Button,
+  Card,
+  CardBody,
+  CardHeader,
+  Content,
+  EmptyState,
+  EmptyStateBody,
+  Flex,
+  FlexItem,
+  Label,
+  PageSection,
+  Spinner,
+} from '@patternfly/react-core';
+import { AngleLeftIcon, AngleRightIcon } from '@patternfly/react-icons';
+import { CatFact, CatFactGVK, CatFactV1beta1GVK } from '../models/CatFact';
+import { CatFactDeck } from '../models/CatFactDeck';
+import CatIcon from './CatIcon';
+
+type PageComponentProps = { obj?: CatFactDeck };
+
+// Shows a CatFactDeck's members one at a time, starting at the deck's current
+// member. Follows the deck when its current member advances.
+const CatFactDeckCarousel: React.FC<PageComponentProps> = ({ obj }) => {
+  const members = obj?.status?.members ?? [];
+  const current = obj?.status?.current ?? 0;
+  const [index, setIndex] = React.useState(current);
+
+  React.useEffect(() => setIndex(current), [current]);
+
+  const [catFacts, loaded, loadError] = useK8sWatchResource<CatFact[]>({
+    groupVersionKind: CatFactV1beta1GVK,
+    isList: true,
+    namespace: obj?.metadata?.namespace,
+  });
+
+  if (!loaded) return <Spinner />;
+  if (loadError || members.length === 0) {
+    return (
+      <EmptyState>
+        <EmptyStateBody>This deck has no Cat Facts.</EmptyStateBody>
+      </EmptyState>
+    );
+  }
+
+  const shown = Math.min(index, members.length - 1);
+  const name = members[shown];
+  const catFact = catFacts.find((cf) => cf.metadata?.name === name);
+  const step = (delta: number) => setIndex((shown + delta + members.length) % members.length);
+
+  return (
+    <PageSection>
+      <Flex alignItems={{ default: 'alignItemsCenter' }} flexWrap={{ default: 'nowrap' }}>
+        <FlexItem>
+          <Button variant="plain" aria-label="Previous Cat Fact" onClick={() => step(-1)}>
+            <AngleLeftIcon />
+          </Button>
+        </FlexItem>
+        <FlexItem grow={{ default: 'grow' }}>
+          <Card isLarge>
+            <CardHeader>
+              <Flex alignItems={{ default: 'alignItemsCenter' }}>
+                <FlexItem>
+                  <CatIcon iconName={catFact?.status?.iconName ?? catFact?.spec.iconName} size={48} />
+                </FlexItem>
+                <FlexItem>
+                  <ResourceLink groupVersionKind={CatFactGVK} name={name} namespace={obj?.metadata?.namespace} />
+                </FlexItem>
+                {shown === current && (
+                  <FlexItem>
+                    <Label color="blue" isCompact>Current</Label>
+                  </FlexItem>
+                )}
+              </Flex>
+            </CardHeader>
+            <CardBody>
+              <Content component="p">{catFact?.status?.fact ?? catFact?.spec.fact ?? ''}</Content>
+            </CardBody>
+          </Card>
+        </FlexItem>
+        <FlexItem>
+          <Button variant="plain" aria-label="Next Cat Fact" onClick={() => step(1)}>
+            <AngleRightIcon />
+          </Button>
+        </FlexItem>
+      </Flex>
+      <Content component="small">
+        {shown + 1} of {members.length}
+      </Content>
+    </PageSection>
+  );
+};
+
+export default CatFactDeckCarousel;
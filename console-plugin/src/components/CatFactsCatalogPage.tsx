@@ -36,11 +36,11 @@ import {
   Spinner,
 } from '@patternfly/react-core';
 import { CatFact, CatFactGVK, CatFactModel } from '../models/CatFact';
+import { useIconOptions } from '../api/backend';
 import CatIcon from './CatIcon';
 import './cat-facts.css';
 
 const ALL_NAMESPACES_KEY = '#ALL_NS#';
-const ICON_OPTIONS = ['Crying', 'Evil', 'Grinning', 'Hearts', 'Joy', 'Kissing', 'Pouting', 'Smiling', 'Weary'];
 
 type SortOrder = 'relevance' | 'asc' | 'desc';
 const SORT_LABELS: Record<SortOrder, string> = { relevance: 'Relevance', asc: 'A-Z', desc: 'Z-A' };
@@ -53,6 +53,7 @@ export default function CatFactsCatalogPage({ namespace }: CatFactsCatalogPagePr
   const [selectedCategory, setSelectedCategory] = React.useState('all');
   const [sortOrder, setSortOrder] = React.useState<SortOrder>('relevance');
   const [sortSelectOpen, setSortSelectOpen] = React.useState(false);
+  const [iconOptions] = useIconOptions();
 
   const ns = namespace ?? (activeNamespace === ALL_NAMESPACES_KEY ? undefined : activeNamespace);
 
@@ -154,7 +155,7 @@ export default function CatFactsCatalogPage({ namespace }: CatFactsCatalogPagePr
                   <NavItem isActive={selectedCategory === 'all'} onClick={() => setSelectedCategory('all')}>
                     All items
                   </NavItem>
-                  {ICON_OPTIONS.map((icon) => (
+                  {iconOptions.map((icon) => (
                     <NavItem key={icon} isActive={selectedCategory === icon} onClick={() => setSelectedCategory(icon)}>
                       {icon}
                     </NavItem>
@@ -1,10 +1,10 @@
 import * as React from 'react';
 import CatFactsPage from './CatFactsPage';
 
-type PageComponentProps = { obj?: { metadata?: { name?: string } } };
+type PageComponentProps = { obj?: { metadata?: { name?: string; labels?: { [key: string]: string } } } };
 
 const CatFactsNamespaceTab: React.FC<PageComponentProps> = ({ obj }) => (
-  <CatFactsPage namespace={obj?.metadata?.name} showTitle={false} />
+  <CatFactsPage namespace={obj?.metadata?.name} namespaceLabels={obj?.metadata?.labels} showTitle={false} />
 );
 
 export default CatFactsNamespaceTab;
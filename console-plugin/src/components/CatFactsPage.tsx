@@ -39,7 +39,10 @@ import {
   useDataViewPagination,
 } from '@patternfly/react-data-view';
 import { CatFact, CatFactGVK, CatFactModel } from '../models/CatFact';
+import { ClusterCatFact, ClusterCatFactGVK, isShownInNamespace } from '../models/ClusterCatFact';
+import { useIconOptions } from '../api/backend';
 import CatIcon from './CatIcon';
+import RandomFactPreview from './RandomFactPreview';
 
 type ColKey = 'name' | 'icon' | 'fact' | 'age';
 type ColWidths = Record<ColKey, number>;
@@ -49,16 +52,26 @@ const DEFAULT_COL_WIDTHS: ColWidths = { name: 200, icon: 80, fact: 500, age: 150
 const DEFAULT_PER_PAGE = 20;
 const COL_KEYS: ColKey[] = ['name', 'icon', 'fact', 'age'];
 const COL_LABELS: Record<ColKey, string> = { name: 'Name', icon: 'Icon', fact: 'Fact', age: 'Age' };
-const ICON_OPTIONS = ['Crying', 'Evil', 'Grinning', 'Hearts', 'Joy', 'Kissing', 'Pouting', 'Smiling', 'Weary'];
 
-type CatFactsPageProps = { namespace?: string; showTitle?: boolean };
+type CatFactsPageProps = {
+  namespace?: string;
+  // Labels of the namespace, used to select the ClusterCatFacts shown in it
+  namespaceLabels?: { [key: string]: string };
+  showTitle?: boolean;
+};
 
-export default function CatFactsPage({ namespace, showTitle = true }: CatFactsPageProps) {
+export default function CatFactsPage({ namespace, namespaceLabels, showTitle = true }: CatFactsPageProps) {
   const [catFacts, loaded, loadError] = useK8sWatchResource<CatFact[]>({
     groupVersionKind: CatFactGVK,
     isList: true,
     namespace,
   });
+  // ClusterCatFacts are optional. Users who can't list them only see CatFacts.
+  const [clusterCatFacts] = useK8sWatchResource<ClusterCatFact[]>({
+    groupVersionKind: ClusterCatFactGVK,
+    isList: true,
+  });
+  const [iconOptions] = useIconOptions();
 
   const [sortBy, setSortBy] = React.useState<ColKey | undefined>(undefined);
   const [direction, setDirection] = React.useState<'asc' | 'desc'>('asc');
@@ -118,9 +131,20 @@ export default function CatFactsPage({ namespace, showTitle = true }: CatFactsPa
 
   const columns: DataViewTh[] = visibleColKeys.map((k) => allColDefs[k]);
 
+  // ClusterCatFacts are listed like CatFacts, with their resolved fact and
+  // icon. They're the only rows without a namespace.
+  const allCatFacts = React.useMemo(() => {
+    const shown = (clusterCatFacts ?? [])
+      .filter((ccf) => namespace === undefined || isShownInNamespace(ccf, namespaceLabels))
+      .map((ccf): CatFact => ({
+        ...ccf,
+        spec: { fact: ccf.status?.fact ?? ccf.spec.fact, iconName: ccf.status?.iconName ?? ccf.spec.iconName },
+      }));
+    return [...(catFacts ?? []), ...shown];
+  }, [catFacts, clusterCatFacts, namespace, namespaceLabels]);
+
   const sortedData = React.useMemo(() => {
-    if (!catFacts) return [];
-    const sorted = [...catFacts];
+    const sorted = [...allCatFacts];
     if (sortBy === 'name') {
       sorted.sort((a, b) => (a.metadata?.name ?? '').localeCompare(b.metadata?.name ?? ''));
     } else if (sortBy === 'age') {
@@ -131,7 +155,7 @@ export default function CatFactsPage({ namespace, showTitle = true }: CatFactsPa
       );
     }
     return direction === 'desc' ? sorted.reverse() : sorted;
-  }, [catFacts, sortBy, direction]);
+  }, [allCatFacts, sortBy, direction]);
 
   const filteredData = React.useMemo(
     () =>
@@ -151,12 +175,17 @@ export default function CatFactsPage({ namespace, showTitle = true }: CatFactsPa
 
   const rows: DataViewTr[] = paginatedData.map((catFact) => {
     const allCells: Record<ColKey, React.ReactNode> = {
-      name: (
+      name: catFact.metadata?.namespace ? (
         <ResourceLink
           groupVersionKind={CatFactGVK}
           name={catFact.metadata?.name}
           namespace={catFact.metadata?.namespace}
         />
+      ) : (
+        <>
+          <ResourceLink groupVersionKind={ClusterCatFactGVK} name={catFact.metadata?.name} inline />
+          <Label isCompact>Cluster</Label>
+        </>
       ),
       icon: <CatIcon iconName={catFact.spec.iconName} />,
       fact: catFact.spec.fact ?? '',
@@ -230,7 +259,7 @@ export default function CatFactsPage({ namespace, showTitle = true }: CatFactsPa
           )}
         >
           <SelectList>
-            {ICON_OPTIONS.map((icon) => (
+            {iconOptions.map((icon) => (
               <SelectOption key={icon} value={icon}>{icon}</SelectOption>
             ))}
           </SelectList>
@@ -296,6 +325,7 @@ export default function CatFactsPage({ namespace, showTitle = true }: CatFactsPa
         />
       )}
       <ListPageBody>
+        <RandomFactPreview />
         {!loaded && <Spinner />}
         {loadError && <Alert variant="danger" isInline title={String(loadError)} />}
         {loaded && !loadError && (
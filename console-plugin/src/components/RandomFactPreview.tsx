@@ -0,0 +1,35 @@
+import * as React from 'react';
+import { Alert, AlertActionLink, Flex, FlexItem } from '@patternfly/react-core';
+import { RandomFact, fetchRandomFact } from '../api/backend';
+import CatIcon from './CatIcon';
+
+// A fact from the operator's fact source, previewing what new CatFacts get.
+// Nothing is shown if the backend API can't be reached.
+export default function RandomFactPreview() {
+  const [preview, setPreview] = React.useState<RandomFact | null>(null);
+
+  const loadPreview = React.useCallback(() => {
+    fetchRandomFact()
+      .then(setPreview)
+      .catch(() => setPreview(null));
+  }, []);
+
+  React.useEffect(loadPreview, [loadPreview]);
+
+  if (!preview) return null;
+  return (
+    <Alert
+      variant="info"
+      isInline
+      title="Did you know?"
+      actionLinks={<AlertActionLink onClick={loadPreview}>Another fact</AlertActionLink>}
+    >
+      <Flex alignItems={{ default: 'alignItemsCenter' }}>
+        <FlexItem>
+          <CatIcon iconName={preview.iconName} />
+        </FlexItem>
+        <FlexItem>{preview.fact}</FlexItem>
+      </Flex>
+    </Alert>
+  );
+}
@@ -7,6 +7,12 @@ export const CatFactGVK: K8sGroupVersionKind = {
   kind: 'CatFact',
 };
 
+// v1beta1 CatFacts have the generated fact and icon in their status
+export const CatFactV1beta1GVK: K8sGroupVersionKind = {
+  ...CatFactGVK,
+  version: 'v1beta1',
+};
+
 export const CatFactModel: K8sModel = {
   apiGroup: 'ryanmillerc.github.io',
   apiVersion: 'v1alpha1',
@@ -27,4 +33,8 @@ export type CatFact = {
     fact?: string;
     iconName?: string;
   };
+  status?: {
+    fact?: string;
+    iconName?: string;
+  };
 } & K8sResourceCommon;
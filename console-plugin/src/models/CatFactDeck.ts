@@ -0,0 +1,20 @@
+import { K8sGroupVersionKind, K8sResourceCommon } from '@openshift-console/dynamic-plugin-sdk';
+
+export const CatFactDeckGVK: K8sGroupVersionKind = {
+  group: 'ryanmillerc.github.io',
+  version: 'v1beta1',
+  kind: 'CatFactDeck',
+};
+
+export type CatFactDeck = {
+  spec: {
+    members?: { name: string }[];
+    advanceInterval?: string;
+  };
+  status?: {
+    members?: string[];
+    count?: number;
+    current?: number;
+    currentName?: string;
+  };
+} & K8sResourceCommon;
@@ -0,0 +1,44 @@
+import { K8sGroupVersionKind, K8sResourceCommon, Selector } from '@openshift-console/dynamic-plugin-sdk';
+
+export const ClusterCatFactGVK: K8sGroupVersionKind = {
+  group: 'ryanmillerc.github.io',
+  version: 'v1beta1',
+  kind: 'ClusterCatFact',
+};
+
+export type ClusterCatFact = {
+  spec: {
+    fact?: string;
+    iconName?: string;
+    namespaceSelector?: Selector;
+  };
+  status?: {
+    fact?: string;
+    iconName?: string;
+  };
+} & K8sResourceCommon;
+
+type Labels = { [key: string]: string };
+
+// Return true if a ClusterCatFact's namespaceSelector matches a namespace's
+// labels. A ClusterCatFact without a selector is shown in every namespace.
+export const isShownInNamespace = (clusterCatFact: ClusterCatFact, labels: Labels = {}): boolean => {
+  const selector = clusterCatFact.spec.namespaceSelector;
+  if (!selector) return true;
+  const matchLabels = Object.entries(selector.matchLabels ?? {}).every(([key, value]) => labels[key] === value);
+  const matchExpressions = (selector.matchExpressions ?? []).every(({ key, operator, values = [] }) => {
+    switch (operator) {
+      case 'In':
+        return key in labels && values.includes(labels[key]);
+      case 'NotIn':
+        return !(key in labels) || !values.includes(labels[key]);
+      case 'Exists':
+        return key in labels;
+      case 'DoesNotExist':
+        return !(key in labels);
+      default:
+        return false;
+    }
+  });
+  return matchLabels && matchExpressions;
+};
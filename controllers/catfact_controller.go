@@ -18,27 +18,91 @@ package controllers
 
 import (
 	"context"
+	"errors"
 	"reflect"
+	"time"
 
-	"k8s.io/apimachinery/pkg/api/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"golang.org/x/time/rate"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/retry"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 
-	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
 	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
+)
+
+// Reasons for Events emitted about CatFacts
+const (
+	ReasonFactGenerated   = "FactGenerated"
+	ReasonFallbackFact    = "FallbackFactUsed"
+	ReasonProviderError   = "ProviderError"
+	ReasonIconGenerated   = "IconGenerated"
+	ReasonInvalidIconName = "InvalidIconName"
+	ReasonUnknownSource   = "UnknownFactSource"
+	ReasonUpdateConflict  = "UpdateConflict"
+	ReasonNotTranslated   = "NotTranslated"
+	ReasonFactRejected    = "FactRejected"
+	ReasonRolledBack      = "RolledBack"
+	ReasonRollbackFailed  = "RollbackFailed"
 )
 
 // CatFactReconciler reconciles a CatFact object
 type CatFactReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
+
+	// Recorder emits Events about CatFacts. Wrap it in a
+	// RateLimitedRecorder so bulk creation doesn't flood the API.
+	Recorder events.EventRecorder
+
+	// Number of CatFacts reconciled in parallel. Defaults to 1.
+	MaxConcurrentReconciles int
+
+	// Rate limiter for retries. Defaults to controller-runtime's default,
+	// see NewRateLimiter.
+	RateLimiter workqueue.TypedRateLimiter[reconcile.Request]
+
+	// Only reconcile CatFacts when their generation, labels, or annotations
+	// change, so status-only updates don't re-trigger work
+	GenerationChangedPredicate bool
+
+	// Run on every replica instead of only the leader. Set when the cache
+	// only holds this replica's shard of CatFacts, see pkg/sharding.
+	Sharded bool
+}
+
+// Return a workqueue rate limiter that retries each CatFact with exponential
+// backoff from baseDelay to maxDelay, and limits retries across all CatFacts
+// to qps with bursts of burst
+func NewRateLimiter(baseDelay, maxDelay time.Duration, qps float32, burst int) workqueue.TypedRateLimiter[reconcile.Request] {
+	return workqueue.NewTypedMaxOfRateLimiter(
+		workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](baseDelay, maxDelay),
+		&workqueue.TypedBucketRateLimiter[reconcile.Request]{Limiter: rate.NewLimiter(rate.Limit(qps), burst)},
+	)
 }
 
 //+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfacts,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfacts/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfacts/finalizers,verbs=update
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfactvotes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=events.k8s.io,resources=events,verbs=create;patch;update
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -46,38 +110,308 @@ type CatFactReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.13.0/pkg/reconcile
 func (r *CatFactReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.reconcile(ctx, req, "CatFact", func() tacomoev1beta1.CatFactObject {
+		return &tacomoev1beta1.CatFact{}
+	})
+}
+
+// Resolve the fact and iconName of a CatFact or ClusterCatFact of kind.
+// newObject returns an empty object of that kind.
+func (r *CatFactReconciler) reconcile(ctx context.Context, req ctrl.Request, kind string, newObject func() tacomoev1beta1.CatFactObject) (_ ctrl.Result, reterr error) {
+	ctx, span := tracing.Start(ctx, kind+".Reconcile", trace.WithAttributes(
+		attribute.String("k8s.namespace.name", req.Namespace),
+		attribute.String("catfact.name", req.Name),
+	))
+	defer func() {
+		tracing.RecordError(span, reterr)
+		span.End()
+	}()
+
+	// Log lines carry the trace ID so they can be matched to the trace
 	logger := log.FromContext(ctx)
-	instance := &tacomoev1alpha1.CatFact{}
-	err := r.Get(context.TODO(), req.NamespacedName, instance)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		logger = logger.WithValues("trace_id", traceID)
+		ctx = log.IntoContext(ctx, logger)
+	}
+
+	if err := r.rollback(ctx, req, newObject); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// The fact and iconName are resolved into status. The CatFact can change
+	// between reading and patching it. Patches use
+	// optimistic locking so concurrent changes aren't overwritten; on a
+	// conflict, start over from a fresh copy.
+	var instance tacomoev1beta1.CatFactObject
+	var result core.Result
+	var processErr error
+	err := retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		instance = newObject()
+		if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+			return err
+		}
+
+		logger.Info("Processing", "Name", instance.GetName())
+
+		// Make a copy of the original instance we can compare to at the end.
+		orgInstance := instance.DeepCopyObject().(tacomoev1beta1.CatFactObject)
+
+		result, processErr = core.ProcessCatFact(ctx, instance)
+		if errors.Is(processErr, core.ErrInvalidIconName) && features.Enabled(features.InvalidIconFallback) && !instance.GetCatFactSpec().Locked {
+			logger.Info("Replacing invalid iconName", "Name", instance.GetName(), "IconName", instance.GetCatFactSpec().IconName)
+			processErr = core.GenerateIconName(instance)
+			result.IconGenerated = processErr == nil
+			instance.GetCatFactStatus().ObservedGeneration = instance.GetGeneration()
+		}
+		core.RecordRevision(instance, orgInstance.GetCatFactStatus(), time.Now())
+		if catFact, ok := instance.(*tacomoev1beta1.CatFact); ok {
+			if err := r.rate(ctx, catFact); err != nil {
+				return err
+			}
+		}
+		if reflect.DeepEqual(instance, orgInstance) {
+			return nil
+		}
+
+		logger.Info("Updating", "Name", instance.GetName())
+		patch := client.MergeFromWithOptions(orgInstance, client.MergeFromWithOptimisticLock{})
+		err := r.Status().Patch(ctx, instance, patch)
+		if kerrors.IsConflict(err) {
+			logger.Info(kind+" changed while processing, retrying", "Name", instance.GetName())
+		}
+		return err
+	})
 	if err != nil {
-		if errors.IsNotFound(err) {
+		if kerrors.IsNotFound(err) {
 			// Request object could have been deleted after reconcile request
 			return ctrl.Result{}, nil
 		}
+		if kerrors.IsConflict(err) {
+			r.event(ctx, instance, corev1.EventTypeWarning, ReasonUpdateConflict, "Update",
+				"%s kept changing while it was being processed, requeueing", kind)
+		}
 		return ctrl.Result{}, err
 	}
 
-	logger.Info("Processing", "Name", instance.Name)
+	r.recordResult(ctx, instance, result)
 
-	// Make a copy of the original instance we can compare to at the end.
-	orgInstance := instance.DeepCopy()
+	if err := r.mirrorCategoryLabel(ctx, instance); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
 
-	err = core.ProcessCatFact(instance)
-	if err != nil {
-		logger.Error(err, "Error processing", "Name", instance.Name)
+	if processErr != nil {
+		logger.Error(processErr, "Error processing", "Name", instance.GetName())
+		if errors.Is(processErr, core.ErrInvalidIconName) {
+			r.event(ctx, instance, corev1.EventTypeWarning, ReasonInvalidIconName, "Validate", "%v", processErr)
+			// Don't requeue. The CatFact is reconciled again when it's fixed.
+			return ctrl.Result{}, reconcile.TerminalError(processErr)
+		}
+		if errors.Is(processErr, core.ErrUnknownFactSource) {
+			r.event(ctx, instance, corev1.EventTypeWarning, ReasonUnknownSource, "GenerateFact", "%v", processErr)
+			return ctrl.Result{}, reconcile.TerminalError(processErr)
+		}
+		return ctrl.Result{}, processErr
+	}
+
+	// Reconcile again when the generated fact is due to be refreshed, or the
+	// translation retried, whichever is first
+	requeueAfter := result.RefreshAfter
+	if result.RetryTranslationAfter > 0 && (requeueAfter == 0 || result.RetryTranslationAfter < requeueAfter) {
+		requeueAfter = result.RetryTranslationAfter
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// Roll a CatFact back to the revision named by its RollbackAnnotation, if it
+// has one, and remove the annotation. The restored fact and icon are patched
+// into status first, so if the annotation can't be removed the rollback is
+// simply repeated. spec.fact and spec.iconName are set to the restored fact
+// and icon when they would otherwise replace them.
+func (r *CatFactReconciler) rollback(ctx context.Context, req ctrl.Request, newObject func() tacomoev1beta1.CatFactObject) error {
+	logger := log.FromContext(ctx)
+	return retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		instance := newObject()
+		if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
+			return err
+		}
+		value, ok := instance.GetAnnotations()[tacomoev1beta1.RollbackAnnotation]
+		if !ok {
+			return nil
+		}
+
+		orgInstance := instance.DeepCopyObject().(tacomoev1beta1.CatFactObject)
+		revision, err := core.Rollback(instance, value, time.Now())
+		if errors.Is(err, core.ErrLocked) || errors.Is(err, core.ErrRevisionNotFound) {
+			// Retrying won't help, so the annotation is removed
+			r.event(ctx, instance, corev1.EventTypeWarning, ReasonRollbackFailed, "Rollback", "%v", err)
+		} else if err != nil {
+			return err
+		} else if !reflect.DeepEqual(instance.GetCatFactStatus(), orgInstance.GetCatFactStatus()) {
+			logger.Info("Rolling back", "Name", instance.GetName(), "Revision", revision.Revision)
+			patch := client.MergeFromWithOptions(orgInstance, client.MergeFromWithOptimisticLock{})
+			if err := r.Status().Patch(ctx, instance, patch); err != nil {
+				return err
+			}
+			r.event(ctx, instance, corev1.EventTypeNormal, ReasonRolledBack, "Rollback",
+				"Rolled back to revision %d", revision.Revision)
+		}
+
+		orgInstance = instance.DeepCopyObject().(tacomoev1beta1.CatFactObject)
+		annotations := instance.GetAnnotations()
+		delete(annotations, tacomoev1beta1.RollbackAnnotation)
+		instance.SetAnnotations(annotations)
+		if spec := instance.GetCatFactSpec(); revision != nil {
+			if spec.Fact != "" || revision.Source == "" {
+				spec.Fact = revision.OriginalFact
+				if spec.Fact == "" {
+					spec.Fact = revision.Fact
+				}
+			}
+			if spec.IconName != "" {
+				spec.IconName = revision.IconName
+			}
+		}
+		return r.Patch(ctx, instance, client.MergeFromWithOptions(orgInstance, client.MergeFromWithOptimisticLock{}))
+	})
+}
+
+// Emit Events describing what ProcessCatFact did
+func (r *CatFactReconciler) recordResult(ctx context.Context, instance tacomoev1beta1.CatFactObject, result core.Result) {
+	status := instance.GetCatFactStatus()
+	switch moderation := status.Moderation; {
+	case result.ProviderErr != nil:
+		r.event(ctx, instance, corev1.EventTypeWarning, ReasonProviderError, "GenerateFact",
+			"Unable to get a fact from %s: %v", factSourceName(instance), result.ProviderErr)
+		r.event(ctx, instance, corev1.EventTypeWarning, ReasonFallbackFact, "GenerateFact",
+			"Using placeholder fact %q", core.PlaceholderFact)
+	case !result.FactGenerated:
+	case moderation != nil && moderation.Decision == tacomoev1beta1.ModerationReplaced:
+		r.event(ctx, instance, corev1.EventTypeWarning, ReasonFactRejected, "Moderate",
+			"Rejected %d facts from %s, the last by %s: %s", moderation.Rejected, factSourceName(instance), moderation.Moderator, moderation.Reason)
+		r.event(ctx, instance, corev1.EventTypeWarning, ReasonFallbackFact, "Moderate",
+			"Using placeholder fact %q", core.PlaceholderFact)
+	default:
+		if moderation != nil && moderation.Rejected > 0 {
+			r.event(ctx, instance, corev1.EventTypeWarning, ReasonFactRejected, "Moderate",
+				"Rejected %d facts from %s, the last by %s: %s", moderation.Rejected, factSourceName(instance), moderation.Moderator, moderation.Reason)
+		}
+		r.event(ctx, instance, corev1.EventTypeNormal, ReasonFactGenerated, "GenerateFact",
+			"Generated fact from %s", status.Source)
+	}
+	if result.TranslationErr != nil {
+		r.event(ctx, instance, corev1.EventTypeWarning, ReasonNotTranslated, "Translate",
+			"Showing the fact untranslated: %v", result.TranslationErr)
+	}
+	if result.IconGenerated {
+		r.event(ctx, instance, corev1.EventTypeNormal, ReasonIconGenerated, "GenerateIconName",
+			"Generated iconName %s", status.IconName)
 	}
+}
 
-	if !reflect.DeepEqual(instance, orgInstance) {
-		logger.Info("Updating", "Name", instance.Name)
-		r.Update(ctx, instance)
+// Aggregate the CatFactVotes on a CatFact into its status. Votes are read
+// from the cache; when one changes, the CatFact is reconciled again.
+func (r *CatFactReconciler) rate(ctx context.Context, catFact *tacomoev1beta1.CatFact) error {
+	var votes tacomoev1beta1.CatFactVoteList
+	if err := r.List(ctx, &votes, client.InNamespace(catFact.Namespace),
+		client.MatchingFields{tacomoev1beta1.CatFactNameField: catFact.Name}); err != nil {
+		return err
 	}
+	core.RateCatFact(catFact, votes.Items)
+	return nil
+}
 
-	return ctrl.Result{}, nil
+// Set the category label to status.category so CatFacts can be selected by
+// category
+func (r *CatFactReconciler) mirrorCategoryLabel(ctx context.Context, instance tacomoev1beta1.CatFactObject) error {
+	category := instance.GetCatFactStatus().Category
+	if category == "" || instance.GetLabels()[tacomoev1beta1.CategoryLabel] == category {
+		return nil
+	}
+	orgInstance := instance.DeepCopyObject().(tacomoev1beta1.CatFactObject)
+	labels := instance.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[tacomoev1beta1.CategoryLabel] = category
+	instance.SetLabels(labels)
+	log.FromContext(ctx).Info("Labeling", "Name", instance.GetName(), "Category", category)
+	return r.Patch(ctx, instance, client.MergeFrom(orgInstance))
+}
+
+// Return the name of the fact source a CatFact's fact is generated from
+func factSourceName(instance tacomoev1beta1.CatFactObject) string {
+	if ref := instance.GetCatFactSpec().SourceRef; ref != nil {
+		return ref.Name
+	}
+	return core.DefaultProvider().Name()
+}
+
+// Emit an Event about a CatFact if the CatFactEvents feature is enabled. note
+// is a format for args, so errors and names go in args, never in note. The
+// note ends with the trace ID of the reconcile, if it's traced.
+func (r *CatFactReconciler) event(ctx context.Context, instance tacomoev1beta1.CatFactObject, eventtype, reason, action, note string, args ...interface{}) {
+	if !features.Enabled(features.CatFactEvents) {
+		return
+	}
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		note += " (trace_id=%s)"
+		args = append(args, traceID)
+	}
+	r.Recorder.Eventf(instance, nil, eventtype, reason, action, note, args...)
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *CatFactReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	var predicates []predicate.Predicate
+	if r.GenerationChangedPredicate {
+		// Annotations and labels don't bump the generation, but rollbacks
+		// and the mirrored category label need a reconcile
+		predicates = append(predicates, predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+		))
+	}
+	options := controller.Options{
+		MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+		RateLimiter:             r.RateLimiter,
+	}
+	if r.Sharded {
+		options.NeedLeaderElection = ptr.To(false)
+	}
+
+	// CatFacts are looked up by tag and category, see pkg/backend
+	indexer := mgr.GetFieldIndexer()
+	if err := indexer.IndexField(context.Background(), &tacomoev1beta1.CatFact{}, tacomoev1beta1.TagsField, func(obj client.Object) []string {
+		return obj.(*tacomoev1beta1.CatFact).Spec.Tags
+	}); err != nil {
+		return err
+	}
+	if err := indexer.IndexField(context.Background(), &tacomoev1beta1.CatFact{}, tacomoev1beta1.CategoryField, func(obj client.Object) []string {
+		if category := obj.(*tacomoev1beta1.CatFact).Status.Category; category != "" {
+			return []string{category}
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	// Votes are aggregated into the rating of the CatFact they name
+	if err := indexer.IndexField(context.Background(), &tacomoev1beta1.CatFactVote{}, tacomoev1beta1.CatFactNameField, func(obj client.Object) []string {
+		return []string{obj.(*tacomoev1beta1.CatFactVote).Spec.CatFactName}
+	}); err != nil {
+		return err
+	}
+
 	return ctrl.NewControllerManagedBy(mgr).
-		For(&tacomoev1alpha1.CatFact{}).
+		For(&tacomoev1beta1.CatFact{}, builder.WithPredicates(predicates...)).
+		Watches(&tacomoev1beta1.CatFactVote{}, handler.EnqueueRequestsFromMapFunc(votedCatFact)).
+		WithOptions(options).
 		Complete(r)
 }
+
+// Return a request for the CatFact a CatFactVote is on
+func votedCatFact(_ context.Context, obj client.Object) []reconcile.Request {
+	vote := obj.(*tacomoev1beta1.CatFactVote)
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: vote.Namespace, Name: vote.Spec.CatFactName}}}
+}
@@ -0,0 +1,177 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+)
+
+// Number of CatFacts created by each benchmark iteration
+const benchCatFacts = 1000
+
+// benchProvider returns a fact without calling an external API
+type benchProvider struct{}
+
+func (benchProvider) Name() string                         { return "bench" }
+func (benchProvider) Fact(context.Context) (string, error) { return "Cats are cool!", nil }
+
+// Reconcile 1,000 new CatFacts with the default controller options and with
+// tuned options. Run with:
+//
+//	go test ./controllers -run '^$' -bench ReconcileCatFacts -benchtime 3x
+func BenchmarkReconcileCatFacts(b *testing.B) {
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join("..", "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	cfg, err := testEnv.Start()
+	if err != nil {
+		b.Skipf("envtest is not available: %v", err)
+	}
+	defer testEnv.Stop()
+
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	tacomoev1beta1.AddToScheme(scheme)
+
+	core.SetFactProviders(benchProvider{})
+	defer core.SetFactProviders(&core.CatFactNinjaProvider{URL: core.DefaultFactURL})
+
+	benchmarks := []struct {
+		name       string
+		reconciler CatFactReconciler
+	}{
+		{
+			name: "Default",
+		},
+		{
+			name: "Tuned",
+			reconciler: CatFactReconciler{
+				MaxConcurrentReconciles: 16,
+				RateLimiter: NewRateLimiter(
+					config.DefaultReconcileBaseDelay,
+					config.DefaultReconcileMaxDelay,
+					100,
+					1000,
+				),
+				GenerationChangedPredicate: true,
+			},
+		},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				b.StopTimer()
+				namespace := fmt.Sprintf("bench-%s-%d", bm.name, i)
+				reconciler := bm.reconciler
+				stop := startBenchManager(b, cfg, scheme, namespace, &reconciler)
+				b.StartTimer()
+
+				reconcileCatFacts(b, reconciler.Client, namespace)
+
+				b.StopTimer()
+				stop()
+			}
+		})
+	}
+}
+
+// Start a manager running reconciler for CatFacts in namespace. Returns a
+// function that stops it.
+func startBenchManager(b *testing.B, cfg *rest.Config, scheme *runtime.Scheme, namespace string, reconciler *CatFactReconciler) func() {
+	b.Helper()
+	mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+		Scheme:     scheme,
+		Metrics:    metricsserver.Options{BindAddress: "0"},
+		Cache:      cache.Options{DefaultNamespaces: map[string]cache.Config{namespace: {}}},
+		Controller: ctrlconfig.Controller{SkipNameValidation: ptr.To(true)},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+	reconciler.Client = mgr.GetClient()
+	reconciler.Scheme = mgr.GetScheme()
+	reconciler.Recorder = &events.FakeRecorder{}
+	if err := reconciler.SetupWithManager(mgr); err != nil {
+		b.Fatal(err)
+	}
+
+	apiClient, err := client.New(cfg, client.Options{Scheme: scheme})
+	if err != nil {
+		b.Fatal(err)
+	}
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+	if err := apiClient.Create(context.Background(), ns); err != nil {
+		b.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := mgr.Start(ctx); err != nil {
+			b.Error(err)
+		}
+	}()
+	if !mgr.GetCache().WaitForCacheSync(ctx) {
+		b.Fatal("cache didn't sync")
+	}
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// Create benchCatFacts empty CatFacts in namespace and wait until they all
+// have a fact and iconName
+func reconcileCatFacts(b *testing.B, c client.Client, namespace string) {
+	b.Helper()
+	ctx := context.Background()
+	for i := 0; i < benchCatFacts; i++ {
+		catFact := &tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cat-%d", i), Namespace: namespace},
+		}
+		if err := c.Create(ctx, catFact); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	deadline := time.Now().Add(5 * time.Minute)
+	for time.Now().Before(deadline) {
+		var catFacts tacomoev1beta1.CatFactList
+		if err := c.List(ctx, &catFacts, client.InNamespace(namespace)); err != nil {
+			b.Fatal(err)
+		}
+		done := 0
+		for _, catFact := range catFacts.Items {
+			if catFact.Status.Fact != "" && catFact.Status.IconName != "" {
+				done++
+			}
+		}
+		if done == benchCatFacts {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	b.Fatalf("CatFacts in %s weren't reconciled in time", namespace)
+}
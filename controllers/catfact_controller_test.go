@@ -2,14 +2,34 @@ package controllers
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 
-	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	ctrlconfig "sigs.k8s.io/controller-runtime/pkg/config"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
 )
 
 var _ = Describe("CatFact controller", func() {
@@ -28,16 +48,16 @@ var _ = Describe("CatFact controller", func() {
 		It("Should generate a fact if a fact isn't provided", func() {
 			By("Magic")
 			ctx := context.Background()
-			catFact := &tacomoev1alpha1.CatFact{
+			catFact := &tacomoev1beta1.CatFact{
 				TypeMeta: metav1.TypeMeta{
-					APIVersion: "ryanmillerc.github.io/v1alpha1",
+					APIVersion: "ryanmillerc.github.io/v1beta1",
 					Kind:       "CatFact",
 				},
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      CatFactName,
 					Namespace: CatFactNamespace,
 				},
-				Spec: tacomoev1alpha1.CatFactSpec{
+				Spec: tacomoev1beta1.CatFactSpec{
 					Fact:     "",
 					IconName: "Joy",
 				},
@@ -45,7 +65,7 @@ var _ = Describe("CatFact controller", func() {
 			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
 
 			catFactLookupKey := types.NamespacedName{Name: CatFactName, Namespace: CatFactNamespace}
-			createdCatFact := &tacomoev1alpha1.CatFact{}
+			createdCatFact := &tacomoev1beta1.CatFact{}
 
 			// TODO: Fix this. The eventually block isn't working right. It's
 			// returning either too fast or returning cached data. Sleeping for
@@ -59,7 +79,15 @@ var _ = Describe("CatFact controller", func() {
 			}, timeout, interval).Should(BeTrue())
 
 			// Let's make sure a fact was set on the object
-			Expect(createdCatFact.Spec.Fact).ShouldNot(Equal(""))
+			Expect(createdCatFact.Status.Fact).ShouldNot(Equal(""))
+
+			// The fact's category is mirrored to a label
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, catFactLookupKey, createdCatFact); err != nil {
+					return ""
+				}
+				return createdCatFact.Labels[tacomoev1beta1.CategoryLabel]
+			}, timeout, interval).Should(Equal(createdCatFact.Status.Category))
 
 			// Delete the CatFact so it doesn't conflict with other tests
 			k8sClient.Delete(ctx, createdCatFact)
@@ -68,16 +96,16 @@ var _ = Describe("CatFact controller", func() {
 		It("Should generate an iconName if an iconName isn't provided", func() {
 			By("Magic")
 			ctx := context.Background()
-			catFact := &tacomoev1alpha1.CatFact{
+			catFact := &tacomoev1beta1.CatFact{
 				TypeMeta: metav1.TypeMeta{
-					APIVersion: "ryanmillerc.github.io/v1alpha1",
+					APIVersion: "ryanmillerc.github.io/v1beta1",
 					Kind:       "CatFact",
 				},
 				ObjectMeta: metav1.ObjectMeta{
 					Name:      CatFactName,
 					Namespace: CatFactNamespace,
 				},
-				Spec: tacomoev1alpha1.CatFactSpec{
+				Spec: tacomoev1beta1.CatFactSpec{
 					Fact:     "Cats are cool!",
 					IconName: "",
 				},
@@ -85,7 +113,7 @@ var _ = Describe("CatFact controller", func() {
 			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
 
 			catFactLookupKey := types.NamespacedName{Name: CatFactName, Namespace: CatFactNamespace}
-			createdCatFact := &tacomoev1alpha1.CatFact{}
+			createdCatFact := &tacomoev1beta1.CatFact{}
 
 			// TODO: Fix this. The eventually block isn't working right. It's
 			// returning either too fast or returning cached data. Sleeping for
@@ -102,10 +130,341 @@ var _ = Describe("CatFact controller", func() {
 			Expect(createdCatFact.Name).Should(Equal("my-cat-fact"))
 
 			// Let's make sure a fact was set on the object
-			Expect(createdCatFact.Spec.IconName).ShouldNot(Equal(""))
+			Expect(createdCatFact.Status.IconName).ShouldNot(Equal(""))
 
 			// Delete the CatFact so it doesn't conflict with other tests
 			k8sClient.Delete(ctx, createdCatFact)
 		})
 	})
+
+	Context("When a CatFact changes while it is being reconciled", func() {
+		const (
+			ConflictName = "conflicting-cat-fact"
+			InvalidName  = "invalid-cat-fact"
+		)
+
+		// Return a reconciler whose client clears status.fact on the first
+		// forcedGets reads, so the reconciler always has something to patch
+		// even if the manager's reconciler got to the CatFact first, and calls
+		// beforePatch before every patch is sent to the API server
+		newReconciler := func(forcedGets int, beforePatch func(ctx context.Context)) *CatFactReconciler {
+			apiClient, err := client.NewWithWatch(cfg, client.Options{Scheme: scheme.Scheme})
+			Expect(err).NotTo(HaveOccurred())
+			gets := 0
+			return &CatFactReconciler{
+				Client: interceptor.NewClient(apiClient, interceptor.Funcs{
+					Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+						if err := c.Get(ctx, key, obj, opts...); err != nil {
+							return err
+						}
+						if catFact, ok := obj.(*tacomoev1beta1.CatFact); ok && gets < forcedGets {
+							catFact.Status.Fact = ""
+						}
+						gets++
+						return nil
+					},
+					SubResourcePatch: func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+						beforePatch(ctx)
+						return c.SubResource(subResourceName).Patch(ctx, obj, patch, opts...)
+					},
+				}),
+				Scheme:   scheme.Scheme,
+				Recorder: events.NewFakeRecorder(100),
+			}
+		}
+
+		It("Should retry without losing the concurrent change", func() {
+			ctx := context.Background()
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ConflictName,
+					Namespace: CatFactNamespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := types.NamespacedName{Name: ConflictName, Namespace: CatFactNamespace}
+
+			// Simulate another writer by changing the CatFact right before
+			// the first patch, so the patch hits a conflict.
+			conflicts := 0
+			reconciler := newReconciler(1, func(ctx context.Context) {
+				if conflicts > 0 {
+					return
+				}
+				conflicts++
+				current := &tacomoev1beta1.CatFact{}
+				Expect(k8sClient.Get(ctx, key, current)).Should(Succeed())
+				current.Labels = map[string]string{"concurrent": "write"}
+				Expect(k8sClient.Update(ctx, current)).Should(Succeed())
+			})
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(conflicts).Should(Equal(1))
+
+			reconciled := &tacomoev1beta1.CatFact{}
+			Expect(k8sClient.Get(ctx, key, reconciled)).Should(Succeed())
+			Expect(reconciled.Status.Fact).ShouldNot(Equal(""))
+			Expect(reconciled.Status.IconName).ShouldNot(Equal(""))
+			Expect(reconciled.Labels).Should(HaveKeyWithValue("concurrent", "write"))
+
+			k8sClient.Delete(ctx, reconciled)
+		})
+
+		It("Should return the conflict so the request is requeued if it keeps changing", func() {
+			ctx := context.Background()
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ConflictName,
+					Namespace: CatFactNamespace,
+				},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := types.NamespacedName{Name: ConflictName, Namespace: CatFactNamespace}
+
+			reconciler := newReconciler(100, func(ctx context.Context) {
+				current := &tacomoev1beta1.CatFact{}
+				Expect(k8sClient.Get(ctx, key, current)).Should(Succeed())
+				current.Labels = map[string]string{"changed": time.Now().String()}
+				Expect(k8sClient.Update(ctx, current)).Should(Succeed())
+			})
+
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(kerrors.IsConflict(err)).Should(BeTrue())
+			Expect(errors.Is(err, reconcile.TerminalError(nil))).Should(BeFalse())
+
+			k8sClient.Delete(ctx, catFact)
+		})
+
+		It("Should not requeue a CatFact with an invalid iconName", func() {
+			ctx := context.Background()
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      InvalidName,
+					Namespace: CatFactNamespace,
+				},
+				Spec: tacomoev1beta1.CatFactSpec{
+					Fact:     "Cats are cool!",
+					IconName: "Invalid",
+				},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := types.NamespacedName{Name: InvalidName, Namespace: CatFactNamespace}
+
+			reconciler := newReconciler(0, func(context.Context) {})
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(errors.Is(err, reconcile.TerminalError(nil))).Should(BeTrue())
+
+			k8sClient.Delete(ctx, catFact)
+		})
+
+		It("Should trace the reconcile and add the trace ID to Events", func() {
+			exporter := tracetest.NewInMemoryExporter()
+			provider := tracing.NewTracerProvider(exporter, 1)
+			otel.SetTracerProvider(provider)
+			defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+			ctx := context.Background()
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      InvalidName,
+					Namespace: CatFactNamespace,
+				},
+				Spec: tacomoev1beta1.CatFactSpec{
+					Fact:     "Cats are cool!",
+					IconName: "Invalid",
+				},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := types.NamespacedName{Name: InvalidName, Namespace: CatFactNamespace}
+
+			reconciler := newReconciler(0, func(context.Context) {})
+			recorder := reconciler.Recorder.(*events.FakeRecorder)
+			_, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).To(HaveOccurred())
+			Expect(provider.ForceFlush(ctx)).Should(Succeed())
+
+			// The manager's reconciler is traced too, so find the span for
+			// the trace in the Event
+			var event string
+			Expect(recorder.Events).Should(Receive(&event))
+			Expect(event).Should(ContainSubstring("trace_id="))
+			var reconcileSpan *tracetest.SpanStub
+			for _, span := range exporter.GetSpans() {
+				if span.Name == "CatFact.Reconcile" && strings.Contains(event, span.SpanContext.TraceID().String()) {
+					reconcileSpan = &span
+				}
+			}
+			Expect(reconcileSpan).ShouldNot(BeNil())
+			Expect(reconcileSpan.Status.Code).Should(Equal(codes.Error))
+
+			k8sClient.Delete(ctx, catFact)
+		})
+	})
+
+	Context("When a CatFact is voted on", func() {
+		It("Should aggregate the votes into status.rating", func() {
+			ctx := context.Background()
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "voted-cat-fact",
+					Namespace: CatFactNamespace,
+				},
+				Spec: tacomoev1beta1.CatFactSpec{
+					Fact:     "Cats are cool!",
+					IconName: "Joy",
+				},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := client.ObjectKeyFromObject(catFact)
+
+			for user, value := range map[string]int32{"kitten": 1, "tabby": 1, "tom": -1} {
+				vote := &tacomoev1beta1.CatFactVote{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      tacomoev1beta1.VoteName(catFact.Name, user),
+						Namespace: CatFactNamespace,
+					},
+					Spec: tacomoev1beta1.CatFactVoteSpec{CatFactName: catFact.Name, User: user, Value: value},
+				}
+				Expect(k8sClient.Create(ctx, vote)).Should(Succeed())
+			}
+
+			rated := &tacomoev1beta1.CatFact{}
+			Eventually(func() []int32 {
+				if err := k8sClient.Get(ctx, key, rated); err != nil {
+					return nil
+				}
+				return []int32{rated.Status.Rating, rated.Status.Votes}
+			}, timeout, interval).Should(Equal([]int32{1, 3}))
+
+			// Retracting a vote changes the rating
+			Expect(k8sClient.Delete(ctx, &tacomoev1beta1.CatFactVote{ObjectMeta: metav1.ObjectMeta{
+				Name:      tacomoev1beta1.VoteName(catFact.Name, "tom"),
+				Namespace: CatFactNamespace,
+			}})).Should(Succeed())
+			Eventually(func() []int32 {
+				if err := k8sClient.Get(ctx, key, rated); err != nil {
+					return nil
+				}
+				return []int32{rated.Status.Rating, rated.Status.Votes}
+			}, timeout, interval).Should(Equal([]int32{2, 2}))
+
+			k8sClient.Delete(ctx, rated)
+		})
+	})
+
+	Context("When a CatFact is rolled back", func() {
+		It("Should restore the revision named by the rollback-to annotation", func() {
+			ctx := context.Background()
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "rolled-back-cat-fact",
+					Namespace: CatFactNamespace,
+				},
+				Spec: tacomoev1beta1.CatFactSpec{
+					Fact:     "Cats are cool!",
+					IconName: "Joy",
+				},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := client.ObjectKeyFromObject(catFact)
+
+			current := &tacomoev1beta1.CatFact{}
+			Eventually(func() int64 {
+				if err := k8sClient.Get(ctx, key, current); err != nil {
+					return 0
+				}
+				return current.Status.Revision
+			}, timeout, interval).Should(Equal(int64(1)))
+
+			current.Spec.Fact = "Cats sleep a lot"
+			Expect(k8sClient.Update(ctx, current)).Should(Succeed())
+			Eventually(func() int64 {
+				if err := k8sClient.Get(ctx, key, current); err != nil {
+					return 0
+				}
+				return current.Status.Revision
+			}, timeout, interval).Should(Equal(int64(2)))
+			Expect(current.Status.History).Should(HaveLen(1))
+
+			current.Annotations = map[string]string{tacomoev1beta1.RollbackAnnotation: "1"}
+			Expect(k8sClient.Update(ctx, current)).Should(Succeed())
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, key, current); err != nil {
+					return false
+				}
+				_, annotated := current.Annotations[tacomoev1beta1.RollbackAnnotation]
+				return !annotated && current.Status.Revision == 1
+			}, timeout, interval).Should(BeTrue())
+			Expect(current.Spec.Fact).Should(Equal("Cats are cool!"))
+			Expect(current.Status.Fact).Should(Equal("Cats are cool!"))
+			Expect(current.Status.History[0].Revision).Should(Equal(int64(2)))
+
+			k8sClient.Delete(ctx, current)
+		})
+
+		It("Should roll back when only generation changes are reconciled", func() {
+			ctx := context.Background()
+			const namespace = "generation-changed"
+			Expect(k8sClient.Create(ctx, &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}})).Should(Succeed())
+
+			// The suite's manager only reconciles the default namespace
+			mgr, err := ctrl.NewManager(cfg, ctrl.Options{
+				Scheme:     scheme.Scheme,
+				Metrics:    metricsserver.Options{BindAddress: "0"},
+				Cache:      cache.Options{DefaultNamespaces: map[string]cache.Config{namespace: {}}},
+				Controller: ctrlconfig.Controller{SkipNameValidation: ptr.To(true)},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect((&CatFactReconciler{
+				Client:                     mgr.GetClient(),
+				Scheme:                     mgr.GetScheme(),
+				Recorder:                   events.NewFakeRecorder(100),
+				GenerationChangedPredicate: true,
+			}).SetupWithManager(mgr)).Should(Succeed())
+			mgrCtx, stop := context.WithCancel(ctx)
+			defer stop()
+			go func() {
+				defer GinkgoRecover()
+				Expect(mgr.Start(mgrCtx)).Should(Succeed())
+			}()
+
+			catFact := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{Name: "rolled-back-cat-fact", Namespace: namespace},
+				Spec:       tacomoev1beta1.CatFactSpec{Fact: "Cats are cool!", IconName: "Joy"},
+			}
+			Expect(k8sClient.Create(ctx, catFact)).Should(Succeed())
+			key := client.ObjectKeyFromObject(catFact)
+
+			current := &tacomoev1beta1.CatFact{}
+			Eventually(func() int64 {
+				if err := k8sClient.Get(ctx, key, current); err != nil {
+					return 0
+				}
+				return current.Status.Revision
+			}, timeout, interval).Should(Equal(int64(1)))
+			current.Spec.Fact = "Cats sleep a lot"
+			Expect(k8sClient.Update(ctx, current)).Should(Succeed())
+			Eventually(func() int64 {
+				if err := k8sClient.Get(ctx, key, current); err != nil {
+					return 0
+				}
+				return current.Status.Revision
+			}, timeout, interval).Should(Equal(int64(2)))
+
+			// The annotation doesn't change the generation
+			current.Annotations = map[string]string{tacomoev1beta1.RollbackAnnotation: "1"}
+			Expect(k8sClient.Update(ctx, current)).Should(Succeed())
+			Eventually(func() bool {
+				if err := k8sClient.Get(ctx, key, current); err != nil {
+					return false
+				}
+				_, annotated := current.Annotations[tacomoev1beta1.RollbackAnnotation]
+				return !annotated && current.Status.Revision == 1
+			}, timeout, interval).Should(BeTrue())
+			Expect(current.Status.Fact).Should(Equal("Cats are cool!"))
+
+			k8sClient.Delete(ctx, current)
+		})
+	})
 })
@@ -0,0 +1,220 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"slices"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+)
+
+// Reasons for Events emitted about CatFactDecks
+const (
+	ReasonMemberCreated   = "MemberCreated"
+	ReasonInvalidSelector = "InvalidSelector"
+)
+
+// CatFactDeckReconciler reconciles a CatFactDeck object
+type CatFactDeckReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Recorder emits Events about CatFactDecks
+	Recorder events.EventRecorder
+
+	// Reader used to list CatFacts. Defaults to Client. When sharding, the
+	// cache only holds this replica's shard of CatFacts, so set it to a
+	// reader that isn't limited to the shard, see pkg/sharding.
+	CatFactReader client.Reader
+
+	// Cache CatFact changes are watched from, so decks follow members in
+	// every shard. Only CatFact metadata is watched. Defaults to the
+	// manager's cache, which when sharding only holds this replica's shard.
+	CatFactCache cache.Cache
+}
+
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfactdecks,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfactdecks/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfactdecks/finalizers,verbs=update
+
+// Reconcile creates a CatFactDeck's missing members from their templates,
+// resolves its members into status, and advances its current member
+func (r *CatFactDeckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	deck := &tacomoev1beta1.CatFactDeck{}
+	if err := r.Get(ctx, req.NamespacedName, deck); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	var catFacts tacomoev1beta1.CatFactList
+	if err := r.catFactReader().List(ctx, &catFacts, client.InNamespace(deck.Namespace)); err != nil {
+		return ctrl.Result{}, err
+	}
+	created, err := r.createMissingMembers(ctx, deck, catFacts.Items)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	catFacts.Items = append(catFacts.Items, created...)
+
+	orgDeck := deck.DeepCopy()
+	advanceAfter, err := core.ProcessCatFactDeck(deck, catFacts.Items, time.Now())
+	if errors.Is(err, core.ErrInvalidSelector) {
+		r.event(deck, corev1.EventTypeWarning, ReasonInvalidSelector, "ResolveMembers", "%v", err)
+		// Don't requeue. The deck is reconciled again when it's fixed.
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !reflect.DeepEqual(deck.Status, orgDeck.Status) {
+		logger.Info("Updating", "Name", deck.Name, "Count", deck.Status.Count, "Current", deck.Status.CurrentName)
+		if err := r.Status().Patch(ctx, deck, client.MergeFrom(orgDeck)); err != nil {
+			return ctrl.Result{}, client.IgnoreNotFound(err)
+		}
+	}
+
+	// Reconcile again when the current member is due to advance
+	return ctrl.Result{RequeueAfter: advanceAfter}, nil
+}
+
+// Create the CatFacts of members that don't exist and have a template.
+// Returns the created CatFacts.
+func (r *CatFactDeckReconciler) createMissingMembers(ctx context.Context, deck *tacomoev1beta1.CatFactDeck, catFacts []tacomoev1beta1.CatFact) ([]tacomoev1beta1.CatFact, error) {
+	var created []tacomoev1beta1.CatFact
+	for _, member := range deck.Spec.Members {
+		if member.Template == nil || slices.ContainsFunc(catFacts, func(c tacomoev1beta1.CatFact) bool { return c.Name == member.Name }) {
+			continue
+		}
+
+		catFact := &tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      member.Name,
+				Namespace: deck.Namespace,
+				Labels:    map[string]string{},
+			},
+			Spec: *member.Template.Spec.DeepCopy(),
+		}
+		for k, v := range member.Template.Labels {
+			catFact.Labels[k] = v
+		}
+		catFact.Labels[tacomoev1beta1.DeckLabel] = deck.Name
+		if err := controllerutil.SetControllerReference(deck, catFact, r.Scheme); err != nil {
+			return nil, err
+		}
+
+		err := r.Create(ctx, catFact)
+		if kerrors.IsAlreadyExists(err) {
+			// Created since CatFacts were listed, e.g. by a user. It's
+			// picked up when the deck is reconciled again.
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		log.FromContext(ctx).Info("Created deck member", "Name", deck.Name, "Member", member.Name)
+		r.event(deck, corev1.EventTypeNormal, ReasonMemberCreated, "CreateMember",
+			"Created CatFact %s from its template", member.Name)
+		created = append(created, *catFact)
+	}
+	return created, nil
+}
+
+// Return the reader CatFacts are listed with
+func (r *CatFactDeckReconciler) catFactReader() client.Reader {
+	if r.CatFactReader != nil {
+		return r.CatFactReader
+	}
+	return r.Client
+}
+
+// Emit an Event about a CatFactDeck if the CatFactEvents feature is enabled
+func (r *CatFactDeckReconciler) event(deck *tacomoev1beta1.CatFactDeck, eventtype, reason, action, note string, args ...interface{}) {
+	if !features.Enabled(features.CatFactEvents) {
+		return
+	}
+	r.Recorder.Eventf(deck, nil, eventtype, reason, action, note, args...)
+}
+
+// Return requests for the decks in a CatFact's namespace that name it, select
+// it, or had it as a member
+func (r *CatFactDeckReconciler) decksForCatFact(ctx context.Context, obj client.Object) []reconcile.Request {
+	var decks tacomoev1beta1.CatFactDeckList
+	if err := r.List(ctx, &decks, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "unable to list CatFactDecks", "namespace", obj.GetNamespace())
+		return nil
+	}
+
+	var requests []reconcile.Request
+	for _, deck := range decks.Items {
+		if deckMayContain(&deck, obj) {
+			requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&deck)})
+		}
+	}
+	return requests
+}
+
+// Return true if a CatFact is or was a member of a deck
+func deckMayContain(deck *tacomoev1beta1.CatFactDeck, obj client.Object) bool {
+	if slices.Contains(deck.Status.Members, obj.GetName()) {
+		return true
+	}
+	if slices.ContainsFunc(deck.Spec.Members, func(m tacomoev1beta1.DeckMember) bool { return m.Name == obj.GetName() }) {
+		return true
+	}
+	if deck.Spec.Selector == nil {
+		return false
+	}
+	selector, err := metav1.LabelSelectorAsSelector(deck.Spec.Selector)
+	return err == nil && selector.Matches(labels.Set(obj.GetLabels()))
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CatFactDeckReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	builder := ctrl.NewControllerManagedBy(mgr).
+		For(&tacomoev1beta1.CatFactDeck{})
+	if r.CatFactCache != nil {
+		catFacts := &metav1.PartialObjectMetadata{}
+		catFacts.SetGroupVersionKind(tacomoev1beta1.GroupVersion.WithKind("CatFact"))
+		builder = builder.WatchesRawSource(source.Kind[client.Object](
+			r.CatFactCache, catFacts, handler.EnqueueRequestsFromMapFunc(r.decksForCatFact)))
+	} else {
+		builder = builder.Watches(&tacomoev1beta1.CatFact{}, handler.EnqueueRequestsFromMapFunc(r.decksForCatFact))
+	}
+	return builder.Complete(r)
+}
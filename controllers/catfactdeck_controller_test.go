@@ -0,0 +1,74 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("CatFactDeck controller", func() {
+
+	const (
+		DeckName      = "onboarding"
+		DeckNamespace = "default"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When creating a CatFactDeck", func() {
+		It("Should create missing members from templates and resolve its members", func() {
+			ctx := context.Background()
+			existing := &tacomoev1beta1.CatFact{
+				ObjectMeta: metav1.ObjectMeta{Name: "deck-existing", Namespace: DeckNamespace},
+				Spec:       tacomoev1beta1.CatFactSpec{Fact: "Cats have five toes on their front paws.", IconName: "Joy"},
+			}
+			Expect(k8sClient.Create(ctx, existing)).Should(Succeed())
+
+			deck := &tacomoev1beta1.CatFactDeck{
+				ObjectMeta: metav1.ObjectMeta{Name: DeckName, Namespace: DeckNamespace},
+				Spec: tacomoev1beta1.CatFactDeckSpec{
+					Members: []tacomoev1beta1.DeckMember{
+						{
+							Name: "deck-templated",
+							Template: &tacomoev1beta1.CatFactTemplate{
+								Spec: tacomoev1beta1.CatFactSpec{Fact: "Cats can rotate their ears 180 degrees.", IconName: "Hearts"},
+							},
+						},
+						{Name: "deck-existing"},
+						{Name: "deck-missing"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, deck)).Should(Succeed())
+
+			created := &tacomoev1beta1.CatFact{}
+			Eventually(func() error {
+				return k8sClient.Get(ctx, types.NamespacedName{Name: "deck-templated", Namespace: DeckNamespace}, created)
+			}, timeout, interval).Should(Succeed())
+			Expect(created.Labels).Should(HaveKeyWithValue(tacomoev1beta1.DeckLabel, DeckName))
+			Expect(metav1.IsControlledBy(created, deck)).Should(BeTrue())
+
+			resolved := &tacomoev1beta1.CatFactDeck{}
+			Eventually(func() int32 {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: DeckName, Namespace: DeckNamespace}, resolved); err != nil {
+					return 0
+				}
+				return resolved.Status.Count
+			}, timeout, interval).Should(Equal(int32(2)))
+			Expect(resolved.Status.Members).Should(Equal([]string{"deck-templated", "deck-existing"}))
+			Expect(resolved.Status.MissingMembers).Should(Equal([]string{"deck-missing"}))
+			Expect(resolved.Status.CurrentName).Should(Equal("deck-templated"))
+
+			Expect(k8sClient.Delete(ctx, resolved)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, existing)).Should(Succeed())
+			Expect(k8sClient.Delete(ctx, created)).Should(Succeed())
+		})
+	})
+})
@@ -0,0 +1,67 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// ClusterCatFactReconciler reconciles a ClusterCatFact object. Its fact and
+// iconName are resolved the same way as a CatFact's, with the same options.
+// ClusterCatFacts aren't sharded, they're reconciled by the leader.
+type ClusterCatFactReconciler struct {
+	CatFactReconciler
+}
+
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=clustercatfacts,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=clustercatfacts/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=ryanmillerc.github.io,resources=clustercatfacts/finalizers,verbs=update
+
+// Reconcile resolves a ClusterCatFact's fact and iconName into its status
+func (r *ClusterCatFactReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return r.reconcile(ctx, req, "ClusterCatFact", func() tacomoev1beta1.CatFactObject {
+		return &tacomoev1beta1.ClusterCatFact{}
+	})
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ClusterCatFactReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	var predicates []predicate.Predicate
+	if r.GenerationChangedPredicate {
+		// Annotations and labels don't bump the generation, but rollbacks
+		// and the mirrored category label need a reconcile
+		predicates = append(predicates, predicate.Or(
+			predicate.GenerationChangedPredicate{},
+			predicate.AnnotationChangedPredicate{},
+			predicate.LabelChangedPredicate{},
+		))
+	}
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&tacomoev1beta1.ClusterCatFact{}, builder.WithPredicates(predicates...)).
+		WithOptions(controller.Options{
+			MaxConcurrentReconciles: r.MaxConcurrentReconciles,
+			RateLimiter:             r.RateLimiter,
+		}).
+		Complete(r)
+}
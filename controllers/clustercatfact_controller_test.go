@@ -0,0 +1,51 @@
+package controllers
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("ClusterCatFact controller", func() {
+
+	const (
+		ClusterCatFactName = "my-cluster-cat-fact"
+
+		timeout  = time.Second * 10
+		interval = time.Millisecond * 250
+	)
+
+	Context("When creating a ClusterCatFact", func() {
+		It("Should resolve its fact and iconName like a CatFact", func() {
+			ctx := context.Background()
+			clusterCatFact := &tacomoev1beta1.ClusterCatFact{
+				ObjectMeta: metav1.ObjectMeta{Name: ClusterCatFactName},
+				Spec: tacomoev1beta1.ClusterCatFactSpec{
+					CatFactSpec: tacomoev1beta1.CatFactSpec{Fact: "Cats rule every namespace."},
+					NamespaceSelector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"cat-facts": "enabled"},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, clusterCatFact)).Should(Succeed())
+
+			created := &tacomoev1beta1.ClusterCatFact{}
+			Eventually(func() string {
+				if err := k8sClient.Get(ctx, types.NamespacedName{Name: ClusterCatFactName}, created); err != nil {
+					return ""
+				}
+				return created.Status.IconName
+			}, timeout, interval).ShouldNot(BeEmpty())
+			Expect(created.Status.Fact).Should(Equal("Cats rule every namespace."))
+			Expect(created.Status.ObservedGeneration).Should(Equal(created.Generation))
+
+			Expect(k8sClient.Delete(ctx, created)).Should(Succeed())
+		})
+	})
+})
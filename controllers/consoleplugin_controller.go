@@ -0,0 +1,101 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controllers
+
+import (
+	"context"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/builder"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/predicate"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/console"
+)
+
+// ConsolePluginReconciler manages the lifecycle of the console plugin
+// resources. It reconciles the operator's own Deployment, which is used as an
+// anchor to remove cluster-scoped plugin resources on uninstall. Changes to
+// the plugin serving certificate Secret are mapped to the anchor so rotations
+// roll the plugin Deployment.
+type ConsolePluginReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+
+	// Anchor is the key of the operator's own Deployment
+	Anchor client.ObjectKey
+}
+
+// Reconcile adds the cleanup finalizer to the anchor Deployment and keeps the
+// plugin Deployment in sync with its serving certificate. Once the anchor is
+// being deleted, console plugin resources are removed and the finalizer is
+// released.
+func (r *ConsolePluginReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+	anchor := &appsv1.Deployment{}
+	err := r.Get(ctx, req.NamespacedName, anchor)
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	if anchor.DeletionTimestamp.IsZero() {
+		if controllerutil.AddFinalizer(anchor, console.CleanupFinalizer) {
+			logger.Info("Adding cleanup finalizer", "Name", anchor.Name)
+			if err := r.Update(ctx, anchor); err != nil {
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, console.SyncServingCert(ctx, r.Client)
+	}
+
+	if !controllerutil.ContainsFinalizer(anchor, console.CleanupFinalizer) {
+		return ctrl.Result{}, nil
+	}
+
+	logger.Info("Operator is being removed, cleaning up console plugin", "Name", anchor.Name)
+	if err := console.Cleanup(ctx, r.Client); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	controllerutil.RemoveFinalizer(anchor, console.CleanupFinalizer)
+	return ctrl.Result{}, r.Update(ctx, anchor)
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ConsolePluginReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	isAnchor := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return client.ObjectKeyFromObject(obj) == r.Anchor
+	})
+	isServingCert := predicate.NewPredicateFuncs(func(obj client.Object) bool {
+		return obj.GetNamespace() == r.Anchor.Namespace && obj.GetName() == console.ServingCertSecretName()
+	})
+	toAnchor := handler.EnqueueRequestsFromMapFunc(func(context.Context, client.Object) []reconcile.Request {
+		return []reconcile.Request{{NamespacedName: r.Anchor}}
+	})
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("consoleplugin").
+		For(&appsv1.Deployment{}, builder.WithPredicates(isAnchor)).
+		Watches(&corev1.Secret{}, toAnchor, builder.WithPredicates(isServingCert)).
+		Complete(r)
+}
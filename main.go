@@ -17,24 +17,48 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"os"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	consolev1 "github.com/openshift/api/console/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/fields"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
 	"github.com/ryanmillerc/cat-facts-operator/controllers"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/admission"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/backend"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/cluster"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
 	"github.com/ryanmillerc/cat-facts-operator/pkg/console"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/events"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/migration"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/sharding"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -45,8 +69,12 @@ var (
 
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(consolev1.AddToScheme(scheme))
+	utilruntime.Must(operatorv1.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(tacomoev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(tacomoev1beta1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -54,11 +82,76 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var cleanup bool
+	var backendAddr string
+	var backendCertDir string
+	var pluginReplicas int
+	var configFile string
+	var maxConcurrentReconciles int
+	var eventQPS float64
+	var eventBurst int
+	var featureGates string
+	var reconcileBaseDelay time.Duration
+	var reconcileMaxDelay time.Duration
+	var reconcileQPS float64
+	var reconcileBurst int
+	var generationChangedPredicate bool
+	var tracingEndpoint string
+	var tracingSampleRatio float64
+	var enableSharding bool
+	var shardID string
+	pluginOptions := console.DefaultPluginOptions()
+	flag.StringVar(&configFile, "config", "",
+		"Path to an OperatorConfig file. Flags set on the command line override values from the file.")
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.StringVar(&backendAddr, "backend-bind-address", fmt.Sprintf(":%d", config.BackendPort),
+		"The address the backend API for the console plugin binds to. Set to 0 to disable.")
+	flag.StringVar(&backendCertDir, "backend-cert-dir", "/tmp/k8s-backend-server/serving-certs",
+		"Directory with tls.crt and tls.key for the backend API. Plain HTTP is served if they don't exist.")
+	flag.BoolVar(&cleanup, "cleanup", false,
+		"Remove console plugin resources left behind by the operator and exit. "+
+			"Use this for scripted teardown after the operator has been stopped.")
+	flag.IntVar(&pluginReplicas, "console-plugin-replicas", int(pluginOptions.Replicas),
+		"Number of console plugin replicas. Ignored when autoscaling is enabled.")
+	flag.BoolVar(&pluginOptions.PodDisruptionBudget, "console-plugin-pdb", false,
+		"Create a PodDisruptionBudget for the console plugin.")
+	flag.BoolVar(&pluginOptions.NetworkPolicy, "console-plugin-network-policy", false,
+		"Create a NetworkPolicy that only allows ingress to the console plugin from the console namespace.")
+	flag.BoolVar(&pluginOptions.TopologySpread, "console-plugin-topology-spread", false,
+		"Spread console plugin pods across nodes and zones.")
+	flag.BoolVar(&pluginOptions.Autoscaling.Enabled, "console-plugin-autoscaling", false,
+		"Create a HorizontalPodAutoscaler for the console plugin.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", 1,
+		"Number of CatFacts reconciled in parallel.")
+	flag.Float64Var(&eventQPS, "event-qps", float64(events.DefaultQPS),
+		"Sustained rate of Events per second, per Event type, emitted about CatFacts.")
+	flag.IntVar(&eventBurst, "event-burst", events.DefaultBurst,
+		"Number of Events per type that can be emitted about CatFacts in a burst.")
+	flag.DurationVar(&reconcileBaseDelay, "reconcile-base-delay", config.DefaultReconcileBaseDelay,
+		"First delay before a failed CatFact is retried. Doubles on every failure up to --reconcile-max-delay.")
+	flag.DurationVar(&reconcileMaxDelay, "reconcile-max-delay", config.DefaultReconcileMaxDelay,
+		"Longest delay before a failed CatFact is retried.")
+	flag.Float64Var(&reconcileQPS, "reconcile-qps", float64(config.DefaultReconcileQPS),
+		"Sustained retries per second across all CatFacts.")
+	flag.IntVar(&reconcileBurst, "reconcile-burst", config.DefaultReconcileBurst,
+		"Number of retries across all CatFacts that can happen in a burst.")
+	flag.BoolVar(&generationChangedPredicate, "generation-changed-predicate", false,
+		"Only reconcile CatFacts when their spec, labels, or annotations change, ignoring status-only updates.")
+	flag.StringVar(&featureGates, "feature-gates", "", features.DefaultFeatureGate.Usage())
+	flag.BoolVar(&enableSharding, "sharding", false,
+		"Split CatFacts between replicas by hash instead of reconciling them all on the leader. "+
+			"Requires --leader-elect when running more than one replica.")
+	flag.StringVar(&shardID, "shard-id", defaultShardID(),
+		"Identity of this replica in the shard ring. Defaults to POD_NAME or the hostname.")
+	flag.StringVar(&tracingEndpoint, "tracing-endpoint", "",
+		"OTLP/HTTP endpoint traces are exported to, e.g. http://otel-collector:4318/v1/traces. "+
+			"Tracing is disabled if this and OTEL_EXPORTER_OTLP_ENDPOINT are empty.")
+	flag.Float64Var(&tracingSampleRatio, "tracing-sample-ratio", 1,
+		"Fraction of reconciles that are traced.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -67,8 +160,148 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
+	operatorConfig := config.DefaultOperatorConfig()
+	if configFile != "" {
+		var err error
+		operatorConfig, err = config.LoadOperatorConfig(configFile)
+		if err != nil {
+			setupLog.Error(err, "unable to load config file", "path", configFile)
+			os.Exit(1)
+		}
+		// Use values from the file for flags that weren't set
+		setFlags := map[string]bool{}
+		flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+		for name, value := range operatorConfig.FlagValues() {
+			if !setFlags[name] {
+				if err := flag.Set(name, value); err != nil {
+					setupLog.Error(err, "invalid value in config file", "flag", name)
+					os.Exit(1)
+				}
+			}
+		}
+	}
+	operatorConfig.ApplyRuntimeSettings()
+
+	// Feature gates from the flag override those from the config file
+	if err := features.DefaultFeatureGate.SetFromMap(operatorConfig.FeatureGates); err != nil {
+		setupLog.Error(err, "invalid feature gates in config file")
+		os.Exit(1)
+	}
+	if err := features.DefaultFeatureGate.Set(featureGates); err != nil {
+		setupLog.Error(err, "invalid --feature-gates")
+		os.Exit(1)
+	}
+	for _, status := range features.DefaultFeatureGate.Status() {
+		setupLog.Info("feature gate", "name", status.Name, "stage", status.Stage, "enabled", status.Enabled)
+	}
+	pluginOptions.Replicas = int32(pluginReplicas)
+	pluginOptions.Autoscaling.MinReplicas = operatorConfig.ConsolePlugin.Autoscaling.MinReplicas
+	pluginOptions.Autoscaling.MaxReplicas = operatorConfig.ConsolePlugin.Autoscaling.MaxReplicas
+	pluginOptions.Autoscaling.TargetCPUUtilization = operatorConfig.ConsolePlugin.Autoscaling.TargetCPUUtilization
+
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingEndpoint, tracingSampleRatio)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "unable to flush traces")
+		}
+	}()
+
+	if cleanup {
+		setupLog.Info("running one-shot cleanup")
+		if err := console.RunCleanup(context.Background()); err != nil {
+			setupLog.Error(err, "unable to clean up console plugin")
+			os.Exit(1)
+		}
+		setupLog.Info("cleanup complete")
+		return
+	}
+
+	restConfig := ctrl.GetConfigOrDie()
+
+	// Find out which optional APIs (OpenShift console, ClusterVersion) the
+	// cluster serves. Anything OpenShift specific is skipped when the APIs
+	// aren't there, so the operator can also run on vanilla Kubernetes.
+	capabilities, err := cluster.DetectCapabilities(restConfig)
+	if err != nil {
+		setupLog.Error(err, "unable to detect cluster capabilities")
+		os.Exit(1)
+	}
+	setupLog.Info(
+		"detected cluster capabilities",
+		"openShift", capabilities.IsOpenShift(),
+		"openShiftVersion", capabilities.OpenShiftVersion,
+		"consolePlugin", capabilities.SupportsConsolePlugin(),
+	)
+
+	// CatFacts are watched in WATCH_NAMESPACE, or in all namespaces if it's
+	// empty.
+	watchNamespaces, err := config.WatchNamespaces()
+	if err != nil {
+		setupLog.Error(err, "unable to determine namespaces to watch")
+		os.Exit(1)
+	}
+	cacheOptions := cache.Options{}
+	if len(watchNamespaces) > 0 {
+		setupLog.Info("watching namespaces", "namespaces", watchNamespaces)
+		cacheOptions.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range watchNamespaces {
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+	} else {
+		setupLog.Info("watching all namespaces")
+	}
+
+	// When sharding, only this replica's CatFacts are cached. The leader
+	// labels CatFacts with the replica that owns them.
+	var shardNamespace string
+	if enableSharding {
+		shardNamespace, err = console.ControllerNamespace()
+		if err != nil {
+			setupLog.Error(err, "unable to determine namespace for shard leases")
+			os.Exit(1)
+		}
+		setupLog.Info("sharding CatFacts", "shardID", shardID)
+		cacheOptions.ByObject = map[client.Object]cache.ByObject{
+			&tacomoev1beta1.CatFact{}: {Label: sharding.LabelSelector(shardID)},
+		}
+	}
+
+	// The operator's own Deployment anchors cleanup of cluster-scoped
+	// console plugin resources. Only that namespace's Deployments, and the
+	// plugin serving certificate Secret, are cached. The anchor namespace is
+	// set explicitly so it's cached even if it isn't a watched namespace.
+	var anchor client.ObjectKey
+	if capabilities.SupportsConsolePlugin() {
+		anchor, err = console.AnchorKey()
+		if err != nil {
+			setupLog.Error(err, "unable to determine operator deployment")
+			os.Exit(1)
+		}
+		if cacheOptions.ByObject == nil {
+			cacheOptions.ByObject = map[client.Object]cache.ByObject{}
+		}
+		cacheOptions.ByObject[&appsv1.Deployment{}] = cache.ByObject{
+			Namespaces: map[string]cache.Config{anchor.Namespace: {}},
+		}
+		cacheOptions.ByObject[&corev1.Secret{}] = cache.ByObject{
+			Namespaces: map[string]cache.Config{anchor.Namespace: {}},
+			Field:      fields.OneTermEqualSelector("metadata.name", console.ServingCertSecretName()),
+		}
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
+		Scheme: scheme,
+		Cache:  cacheOptions,
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				// Read once during cleanup, not worth an informer
+				DisableFor: []client.Object{&operatorv1.Console{}},
+			},
+		},
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
@@ -93,12 +326,144 @@ func main() {
 	if err = (&controllers.CatFactReconciler{
 		Client: mgr.GetClient(),
 		Scheme: mgr.GetScheme(),
+		Recorder: events.NewRateLimitedRecorder(
+			mgr.GetEventRecorder("catfact-controller"),
+			float32(eventQPS),
+			eventBurst,
+		),
+		MaxConcurrentReconciles: maxConcurrentReconciles,
+		RateLimiter: controllers.NewRateLimiter(
+			reconcileBaseDelay,
+			reconcileMaxDelay,
+			float32(reconcileQPS),
+			reconcileBurst,
+		),
+		GenerationChangedPredicate: generationChangedPredicate,
+		Sharded:                    enableSharding,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "CatFact")
 		os.Exit(1)
 	}
+	// ClusterCatFacts need cluster-wide permissions, which the operator
+	// doesn't have when it only watches some namespaces
+	if len(watchNamespaces) == 0 {
+		if err = (&controllers.ClusterCatFactReconciler{CatFactReconciler: controllers.CatFactReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Recorder: events.NewRateLimitedRecorder(
+				mgr.GetEventRecorder("clustercatfact-controller"),
+				float32(eventQPS),
+				eventBurst,
+			),
+			MaxConcurrentReconciles: maxConcurrentReconciles,
+			RateLimiter: controllers.NewRateLimiter(
+				reconcileBaseDelay,
+				reconcileMaxDelay,
+				float32(reconcileQPS),
+				reconcileBurst,
+			),
+			GenerationChangedPredicate: generationChangedPredicate,
+		}}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ClusterCatFact")
+			os.Exit(1)
+		}
+	} else {
+		setupLog.Info("not reconciling ClusterCatFacts while watching specific namespaces")
+	}
+	// The cached client only sees this replica's CatFacts when sharding, so
+	// the backend API and CatFactDeck controller read from the API server
+	// instead, and the quota webhook and CatFactDeck controller watch
+	// CatFacts in the Sharder's cache
+	backendClient := mgr.GetClient()
+	var catFactInformers cache.Informers = mgr.GetCache()
+	var shardCache cache.Cache
+	if enableSharding {
+		backendClient, shardCache, err = setupSharding(mgr, restConfig, watchNamespaces, shardNamespace, shardID)
+		if err != nil {
+			setupLog.Error(err, "unable to set up sharding")
+			os.Exit(1)
+		}
+		catFactInformers = shardCache
+	}
+	// Curated fact libraries are weighted by the ratings of every CatFact
+	core.SetRatingsReader(backendClient)
+	if err = (&controllers.CatFactDeckReconciler{
+		Client:        mgr.GetClient(),
+		Scheme:        mgr.GetScheme(),
+		Recorder:      mgr.GetEventRecorder("catfactdeck-controller"),
+		CatFactReader: backendClient,
+		CatFactCache:  shardCache,
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CatFactDeck")
+		os.Exit(1)
+	}
+	if capabilities.SupportsConsolePlugin() {
+		if err = (&controllers.ConsolePluginReconciler{
+			Client: mgr.GetClient(),
+			Scheme: mgr.GetScheme(),
+			Anchor: anchor,
+		}).SetupWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create controller", "controller", "ConsolePlugin")
+			os.Exit(1)
+		}
+	}
+	// The conversion and validating webhooks need a serving certificate. Set
+	// ENABLE_WEBHOOKS to false to run the manager locally without one.
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&tacomoev1beta1.CatFact{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "CatFact")
+			os.Exit(1)
+		}
+		if err = admission.SetupWebhooksWithManager(context.Background(), mgr, catFactInformers, watchNamespaces); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "validating")
+			os.Exit(1)
+		}
+	}
+	// CatFacts stored as v1alpha1 are rewritten as v1beta1
+	if err := mgr.Add(&migration.StorageVersionMigrator{
+		Client:     mgr.GetClient(),
+		Reader:     mgr.GetAPIReader(),
+		Namespaces: watchNamespaces,
+	}); err != nil {
+		setupLog.Error(err, "unable to set up storage version migration")
+		os.Exit(1)
+	}
 	//+kubebuilder:scaffold:builder
 
+	if configFile != "" {
+		// Fact sources and icon policy are reloaded when the file changes
+		if err := mgr.Add(&config.ConfigReloader{
+			Path:    configFile,
+			Current: operatorConfig,
+			OnChange: func(_, next *config.OperatorConfig) {
+				next.ApplyRuntimeSettings()
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to set up config reloader")
+			os.Exit(1)
+		}
+	}
+
+	if err := metrics.RegisterCatFactCollector(mgr.GetCache()); err != nil {
+		setupLog.Error(err, "unable to register CatFact metrics")
+		os.Exit(1)
+	}
+
+	if backendAddr != "0" {
+		if err := mgr.Add(&backend.Server{
+			Client:      backendClient,
+			BindAddress: backendAddr,
+			CertDir:     backendCertDir,
+			Namespaces:  watchNamespaces,
+			Providers:   core.FactProviders,
+			// The uncached client used when sharding has no field indexes
+			IndexedCatFacts: !enableSharding,
+		}); err != nil {
+			setupLog.Error(err, "unable to set up backend API server")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -111,7 +476,7 @@ func main() {
 	// Create resources (Deployment, Service, and ConsolePlugin) required
 	// to run the OpenShift dynamic console plugin. This has to be done before
 	// starting the manager, which kicks off the reconsile infinite loop.
-	err = console.DeployConsolePlugin()
+	err = console.DeployConsolePlugin(capabilities, pluginOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to deploy console plugin")
 	}
@@ -122,3 +487,40 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// Return the default shard ID: the pod name, or the hostname outside of a pod
+func defaultShardID() string {
+	if name := os.Getenv("POD_NAME"); name != "" {
+		return name
+	}
+	hostname, _ := os.Hostname()
+	return hostname
+}
+
+// Join the shard ring and set up the Sharder, which only runs on the leader.
+// Returns a client and cache that aren't limited to this replica's shard.
+func setupSharding(mgr ctrl.Manager, restConfig *rest.Config, watchNamespaces []string, namespace, shardID string) (client.Client, cache.Cache, error) {
+	uncached, err := client.New(restConfig, client.Options{Scheme: mgr.GetScheme()})
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := mgr.Add(&sharding.Member{Client: uncached, Namespace: namespace, Identity: shardID}); err != nil {
+		return nil, nil, err
+	}
+
+	shardCache, err := sharding.NewCache(restConfig, mgr.GetScheme(), watchNamespaces, namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := mgr.Add(shardCache); err != nil {
+		return nil, nil, err
+	}
+	if err := (&sharding.Sharder{
+		Client:    mgr.GetClient(),
+		Cache:     shardCache,
+		Namespace: namespace,
+	}).SetupWithManager(mgr); err != nil {
+		return nil, nil, err
+	}
+	return uncached, shardCache, nil
+}
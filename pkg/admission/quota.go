@@ -0,0 +1,205 @@
+package admission
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"sync"
+	"sync/atomic"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	toolscache "k8s.io/client-go/tools/cache"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+)
+
+var quotaLog = ctrl.Log.WithName("quota")
+
+// Annotation on Namespaces overriding the operator-wide maximum number of
+// CatFacts in the namespace. "0" means no limit.
+const MaxCatFactsAnnotation = "catfacts.ryanmillerc.github.io/max-catfacts"
+
+// +kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+
+// Operator-wide maximum number of CatFacts per namespace. Swapped atomically
+// so it can be reloaded while the webhook is serving.
+var defaultMaxCatFacts atomic.Int64
+
+// Return the operator-wide maximum number of CatFacts per namespace. Zero
+// means no limit.
+func DefaultMaxCatFacts() int64 {
+	return defaultMaxCatFacts.Load()
+}
+
+// Set the operator-wide maximum number of CatFacts per namespace. Zero means
+// no limit.
+func SetDefaultMaxCatFacts(max int64) {
+	defaultMaxCatFacts.Store(max)
+}
+
+// QuotaTracker counts CatFacts per namespace from informer events, so
+// checking a namespace's quota doesn't list its CatFacts. Only CatFact
+// metadata is watched. Namespace quota annotations are kept from informer
+// events too.
+type QuotaTracker struct {
+	// Namespaces CatFacts are counted in. Empty means all namespaces.
+	namespaces []string
+
+	mu     sync.Mutex
+	counts map[string]int64
+	// MaxCatFactsAnnotation of each namespace that has one
+	limits map[string]string
+	synced []func() bool
+}
+
+// Return a QuotaTracker counting the CatFacts in informers. The informers
+// must hold every CatFact in namespaces, or every CatFact if namespaces is
+// empty, so when sharding they can't be the manager's cache, which only holds
+// the replica's shard. Namespaces are watched from informers as well.
+func NewQuotaTracker(ctx context.Context, informers cache.Informers, namespaces []string) (*QuotaTracker, error) {
+	t := &QuotaTracker{namespaces: namespaces, counts: map[string]int64{}, limits: map[string]string{}}
+
+	catFacts := &metav1.PartialObjectMetadata{}
+	catFacts.SetGroupVersionKind(tacomoev1beta1.GroupVersion.WithKind("CatFact"))
+	err := t.watch(ctx, informers, catFacts, toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			if catFact, ok := obj.(client.Object); ok {
+				t.add(catFact.GetNamespace(), 1)
+			}
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if catFact, ok := obj.(client.Object); ok {
+				t.add(catFact.GetNamespace(), -1)
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = t.watch(ctx, informers, &corev1.Namespace{}, toolscache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			t.setLimit(obj)
+		},
+		UpdateFunc: func(_, obj interface{}) {
+			t.setLimit(obj)
+		},
+		DeleteFunc: func(obj interface{}) {
+			if tombstone, ok := obj.(toolscache.DeletedFinalStateUnknown); ok {
+				obj = tombstone.Obj
+			}
+			if ns, ok := obj.(client.Object); ok {
+				t.mu.Lock()
+				defer t.mu.Unlock()
+				delete(t.limits, ns.GetName())
+			}
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Handle events from the informer for obj
+func (t *QuotaTracker) watch(ctx context.Context, informers cache.Informers, obj client.Object, handler toolscache.ResourceEventHandler) error {
+	informer, err := informers.GetInformer(ctx, obj)
+	if err != nil {
+		return err
+	}
+	registration, err := informer.AddEventHandler(handler)
+	if err != nil {
+		return err
+	}
+	t.synced = append(t.synced, registration.HasSynced)
+	return nil
+}
+
+func (t *QuotaTracker) setLimit(obj interface{}) {
+	ns, ok := obj.(client.Object)
+	if !ok {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if value, ok := ns.GetAnnotations()[MaxCatFactsAnnotation]; ok {
+		t.limits[ns.GetName()] = value
+	} else {
+		delete(t.limits, ns.GetName())
+	}
+}
+
+func (t *QuotaTracker) add(namespace string, delta int64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.counts[namespace] += delta
+	if t.counts[namespace] <= 0 {
+		delete(t.counts, namespace)
+		metrics.QuotaUsed.DeleteLabelValues(namespace)
+		return
+	}
+	metrics.QuotaUsed.WithLabelValues(namespace).Set(float64(t.counts[namespace]))
+}
+
+// Return the number of CatFacts in namespace
+func (t *QuotaTracker) Count(namespace string) int64 {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.counts[namespace]
+}
+
+// Return the maximum number of CatFacts in namespace: its
+// MaxCatFactsAnnotation, or DefaultMaxCatFacts. Zero means no limit.
+func (t *QuotaTracker) Limit(namespace string) int64 {
+	t.mu.Lock()
+	value, ok := t.limits[namespace]
+	t.mu.Unlock()
+	if !ok {
+		return DefaultMaxCatFacts()
+	}
+	max, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || max < 0 {
+		quotaLog.Info("Invalid quota annotation, using the default quota", "namespace", namespace, "value", value)
+		return DefaultMaxCatFacts()
+	}
+	return max
+}
+
+// Return true if CatFacts in namespace are counted
+func (t *QuotaTracker) Counted(namespace string) bool {
+	return len(t.namespaces) == 0 || slices.Contains(t.namespaces, namespace)
+}
+
+// Return an error if another CatFact can't be created in namespace. Counts
+// come from the cache, so CatFacts created at the same moment may exceed the
+// quota by a few. Namespaces the operator doesn't watch aren't limited,
+// because their CatFacts aren't counted.
+func (t *QuotaTracker) Admit(ctx context.Context, namespace string) error {
+	if !t.Counted(namespace) {
+		quotaLog.V(1).Info("Namespace isn't watched, not enforcing its quota", "namespace", namespace)
+		return nil
+	}
+	for _, synced := range t.synced {
+		if !synced() {
+			return kerrors.NewServiceUnavailable("CatFacts are still being counted, try again shortly")
+		}
+	}
+	limit := t.Limit(namespace)
+	count := t.Count(namespace)
+	if limit == 0 || count < limit {
+		return nil
+	}
+	metrics.QuotaRejections.WithLabelValues(namespace).Inc()
+	return fmt.Errorf("namespace %s already has %d CatFacts, the most it may have. Delete a CatFact first, or ask an administrator to raise the %s annotation on the namespace",
+		namespace, count, MaxCatFactsAnnotation)
+}
@@ -0,0 +1,163 @@
+package admission
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache/informertest"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllertest"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Return a Validator limiting CatFacts in namespaces with a QuotaTracker,
+// and the informers it counts CatFacts and reads Namespaces from. The
+// "limited" namespace allows 2 CatFacts, "unlimited" allows any number, and
+// the others use the default.
+func newQuotaValidator(t *testing.T, namespaces ...string) (*Validator[*tacomoev1beta1.CatFact], *controllertest.FakeInformer, *controllertest.FakeInformer) {
+	scheme := runtime.NewScheme()
+	metav1.AddMetaToScheme(scheme)
+	corev1.AddToScheme(scheme)
+	informers := &informertest.FakeInformers{Scheme: scheme}
+
+	quota, err := NewQuotaTracker(context.Background(), informers, namespaces)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	informer, err := informers.FakeInformerFor(context.Background(), &metav1.PartialObjectMetadata{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	namespaceInformer, err := informers.FakeInformerFor(context.Background(), &corev1.Namespace{})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	namespaceInformer.Add(namespaceWithQuota("limited", "2"))
+	namespaceInformer.Add(namespaceWithQuota("unlimited", "0"))
+	namespaceInformer.Add(namespaceWithQuota("invalid", "lots"))
+	namespaceInformer.Add(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}})
+	return &Validator[*tacomoev1beta1.CatFact]{Resource: "catfacts", Quota: quota}, informer, namespaceInformer
+}
+
+func namespaceWithQuota(name, max string) *corev1.Namespace {
+	return &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Annotations: map[string]string{MaxCatFactsAnnotation: max}}}
+}
+
+func catFactIn(namespace, name string) *tacomoev1beta1.CatFact {
+	return &tacomoev1beta1.CatFact{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+}
+
+func TestQuotaTrackerCount(t *testing.T) {
+	validator, informer, _ := newQuotaValidator(t)
+	quota := validator.Quota
+
+	informer.Add(catFactIn("limited", "one"))
+	informer.Add(catFactIn("limited", "two"))
+	informer.Add(catFactIn("default", "one"))
+	if quota.Count("limited") != 2 || quota.Count("default") != 1 {
+		t.Errorf("Expected 2 and 1 CatFacts, got %d and %d", quota.Count("limited"), quota.Count("default"))
+	}
+
+	informer.Delete(catFactIn("limited", "one"))
+	informer.Delete(catFactIn("default", "one"))
+	if quota.Count("limited") != 1 || quota.Count("default") != 0 {
+		t.Errorf("Expected 1 and 0 CatFacts after deleting, got %d and %d", quota.Count("limited"), quota.Count("default"))
+	}
+}
+
+func TestQuotaTrackerLimit(t *testing.T) {
+	defer SetDefaultMaxCatFacts(DefaultMaxCatFacts())
+	SetDefaultMaxCatFacts(5)
+	validator, _, namespaces := newQuotaValidator(t)
+
+	tests := map[string]int64{"limited": 2, "unlimited": 0, "invalid": 5, "default": 5, "missing": 5}
+	for namespace, expected := range tests {
+		if limit := validator.Quota.Limit(namespace); limit != expected {
+			t.Errorf("%s: expected a limit of %d, got %d", namespace, expected, limit)
+		}
+	}
+
+	// Annotation changes are picked up from the informer
+	namespaces.Update(namespaceWithQuota("limited", "2"), namespaceWithQuota("limited", "3"))
+	namespaces.Update(&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlimited"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "unlimited"}})
+	namespaces.Delete(namespaceWithQuota("invalid", "lots"))
+	tests = map[string]int64{"limited": 3, "unlimited": 5, "invalid": 5}
+	for namespace, expected := range tests {
+		if limit := validator.Quota.Limit(namespace); limit != expected {
+			t.Errorf("%s: expected a limit of %d after updating, got %d", namespace, expected, limit)
+		}
+	}
+}
+
+func TestValidateCreateQuota(t *testing.T) {
+	defer SetDefaultMaxCatFacts(DefaultMaxCatFacts())
+	SetDefaultMaxCatFacts(1)
+	validator, informer, namespaces := newQuotaValidator(t)
+	ctx := context.Background()
+
+	informer.Add(catFactIn("limited", "one"))
+	if _, err := validator.ValidateCreate(ctx, catFactIn("limited", "two")); err != nil {
+		t.Errorf("Expected the second CatFact to be allowed, got %v", err)
+	}
+	informer.Add(catFactIn("limited", "two"))
+	_, err := validator.ValidateCreate(ctx, catFactIn("limited", "three"))
+	if !kerrors.IsForbidden(err) {
+		t.Fatalf("Expected the third CatFact to be forbidden, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "already has 2 CatFacts") || !strings.Contains(err.Error(), MaxCatFactsAnnotation) {
+		t.Errorf("Expected a friendly message, got %q", err)
+	}
+
+	// Limited by the default
+	informer.Add(catFactIn("default", "one"))
+	if _, err := validator.ValidateCreate(ctx, catFactIn("default", "two")); !kerrors.IsForbidden(err) {
+		t.Errorf("Expected the default quota to apply, got %v", err)
+	}
+	informer.Add(catFactIn("unlimited", "one"))
+	if _, err := validator.ValidateCreate(ctx, catFactIn("unlimited", "two")); err != nil {
+		t.Errorf("Expected no limit, got %v", err)
+	}
+
+	// Deleting a CatFact makes room
+	informer.Delete(catFactIn("limited", "one"))
+	if _, err := validator.ValidateCreate(ctx, catFactIn("limited", "three")); err != nil {
+		t.Errorf("Expected the CatFact to be allowed after deleting one, got %v", err)
+	}
+
+	namespaces.Synced = false
+	if _, err := validator.ValidateCreate(ctx, catFactIn("limited", "three")); !kerrors.IsServiceUnavailable(err) {
+		t.Errorf("Expected an unavailable error before Namespaces are synced, got %v", err)
+	}
+	namespaces.Synced = true
+	informer.Synced = false
+	if _, err := validator.ValidateCreate(ctx, catFactIn("limited", "three")); !kerrors.IsServiceUnavailable(err) {
+		t.Errorf("Expected an unavailable error before CatFacts are counted, got %v", err)
+	}
+}
+
+func TestValidateCreateQuotaWatchedNamespaces(t *testing.T) {
+	defer SetDefaultMaxCatFacts(DefaultMaxCatFacts())
+	SetDefaultMaxCatFacts(1)
+	validator, informer, _ := newQuotaValidator(t, "default")
+	ctx := context.Background()
+
+	informer.Add(catFactIn("default", "one"))
+	if _, err := validator.ValidateCreate(ctx, catFactIn("default", "two")); !kerrors.IsForbidden(err) {
+		t.Errorf("Expected the quota to apply in a watched namespace, got %v", err)
+	}
+	// CatFacts in other namespaces aren't counted, so they aren't limited
+	informer.Add(catFactIn("limited", "one"))
+	informer.Add(catFactIn("limited", "two"))
+	if validator.Quota.Counted("limited") {
+		t.Errorf("Expected CatFacts in an unwatched namespace not to be counted")
+	}
+	if _, err := validator.ValidateCreate(ctx, catFactIn("limited", "three")); err != nil {
+		t.Errorf("Expected no quota in an unwatched namespace, got %v", err)
+	}
+}
@@ -0,0 +1,192 @@
+/*
+Validating admission webhooks for CatFacts and ClusterCatFacts.
+
+A locked CatFact's fact and iconName can't be changed. The CRDs reject such
+changes with CEL validation rules too, but those only apply to v1beta1
+requests; the webhook also sees v1alpha1 requests, converted to v1beta1.
+
+The webhook also rejects rolling back a locked CatFact, or to a revision that
+isn't in status.history, with the catfacts.ryanmillerc.github.io/rollback-to
+annotation.
+
+Creating a CatFact in a namespace that already has its maximum number of
+CatFacts is rejected with a message saying how to get more. The maximum is
+the operator-wide quota.maxCatFactsPerNamespace setting, overridden by the
+namespace's catfacts.ryanmillerc.github.io/max-catfacts annotation; 0 means
+no limit:
+
+	kubectl annotate namespace cats catfacts.ryanmillerc.github.io/max-catfacts=20
+
+Unlocking a CatFact requires the "unlock" verb on catfacts (or
+clustercatfacts), on top of update, so curated facts can be edited by people
+who can't unlock them:
+
+	rules:
+	- apiGroups: [ryanmillerc.github.io]
+	  resources: [catfacts]
+	  verbs: [unlock]
+*/
+
+package admission
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// RBAC verb needed to set spec.locked to false
+const UnlockVerb = "unlock"
+
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+
+// +kubebuilder:webhook:path=/validate-ryanmillerc-github-io-v1beta1-catfact,mutating=false,failurePolicy=fail,sideEffects=None,groups=ryanmillerc.github.io,resources=catfacts,verbs=create;update,versions=v1beta1,name=vcatfact.ryanmillerc.github.io,admissionReviewVersions=v1
+// +kubebuilder:webhook:path=/validate-ryanmillerc-github-io-v1beta1-clustercatfact,mutating=false,failurePolicy=fail,sideEffects=None,groups=ryanmillerc.github.io,resources=clustercatfacts,verbs=update,versions=v1beta1,name=vclustercatfact.ryanmillerc.github.io,admissionReviewVersions=v1
+
+// Validator validates CatFacts or ClusterCatFacts
+type Validator[T tacomoev1beta1.CatFactObject] struct {
+	// Client used for SubjectAccessReviews
+	Client client.Client
+
+	// Plural resource name validated, e.g. "catfacts"
+	Resource string
+
+	// Counts CatFacts per namespace. Nil if creating isn't limited.
+	Quota *QuotaTracker
+}
+
+var _ admission.Validator[*tacomoev1beta1.CatFact] = &Validator[*tacomoev1beta1.CatFact]{}
+
+// ValidateCreate rejects CatFacts exceeding their namespace's quota
+func (v *Validator[T]) ValidateCreate(ctx context.Context, obj T) (admission.Warnings, error) {
+	if v.Quota == nil {
+		return nil, nil
+	}
+	if err := v.Quota.Admit(ctx, obj.GetNamespace()); err != nil {
+		if kerrors.IsServiceUnavailable(err) {
+			return nil, err
+		}
+		return nil, v.forbidden(obj, err)
+	}
+	return nil, nil
+}
+
+// ValidateUpdate rejects changes to a locked CatFact's fact and iconName,
+// unlocking by users without the unlock verb, and rolling back to revisions
+// that don't exist or while locked
+func (v *Validator[T]) ValidateUpdate(ctx context.Context, oldObj, newObj T) (admission.Warnings, error) {
+	oldSpec, newSpec := oldObj.GetCatFactSpec(), newObj.GetCatFactSpec()
+	if err := v.validateRollback(oldObj, newObj); err != nil {
+		return nil, err
+	}
+	if !oldSpec.Locked {
+		return nil, nil
+	}
+
+	if newSpec.Fact != oldSpec.Fact {
+		return nil, v.forbidden(newObj, errors.New("spec.fact can't be changed while spec.locked is true"))
+	}
+	if newSpec.IconName != oldSpec.IconName {
+		return nil, v.forbidden(newObj, errors.New("spec.iconName can't be changed while spec.locked is true"))
+	}
+	if !newSpec.Locked {
+		allowed, err := v.canUnlock(ctx, newObj)
+		if err != nil {
+			return nil, err
+		}
+		if !allowed {
+			return nil, v.forbidden(newObj, fmt.Errorf("setting spec.locked to false requires the %q verb on %s", UnlockVerb, v.Resource))
+		}
+	}
+	return nil, nil
+}
+
+// ValidateDelete accepts every CatFact. Locked CatFacts can be deleted.
+func (v *Validator[T]) ValidateDelete(ctx context.Context, obj T) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// Check a RollbackAnnotation added or changed by the update names a revision
+// of the CatFact, and the CatFact isn't locked
+func (v *Validator[T]) validateRollback(oldObj, newObj T) error {
+	value, ok := newObj.GetAnnotations()[tacomoev1beta1.RollbackAnnotation]
+	if !ok || value == oldObj.GetAnnotations()[tacomoev1beta1.RollbackAnnotation] {
+		return nil
+	}
+	if newObj.GetCatFactSpec().Locked {
+		return v.forbidden(newObj, errors.New("can't roll back while spec.locked is true"))
+	}
+	status := oldObj.GetCatFactStatus()
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err == nil && (revision == status.Revision || slices.ContainsFunc(status.History, func(r tacomoev1beta1.FactRevision) bool {
+		return r.Revision == revision
+	})) {
+		return nil
+	}
+	return kerrors.NewBadRequest(fmt.Sprintf("%s: %q is not a revision in status.history", tacomoev1beta1.RollbackAnnotation, value))
+}
+
+// Return true if the user making the request may unlock obj
+func (v *Validator[T]) canUnlock(ctx context.Context, obj T) (bool, error) {
+	req, err := admission.RequestFromContext(ctx)
+	if err != nil {
+		return false, err
+	}
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, val := range req.UserInfo.Extra {
+		extra[k] = authorizationv1.ExtraValue(val)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   req.UserInfo.Username,
+			UID:    req.UserInfo.UID,
+			Groups: req.UserInfo.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: obj.GetNamespace(),
+				Verb:      UnlockVerb,
+				Group:     tacomoev1beta1.GroupVersion.Group,
+				Resource:  v.Resource,
+				Name:      obj.GetName(),
+			},
+		},
+	}
+	if err := v.Client.Create(ctx, review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
+
+func (v *Validator[T]) forbidden(obj T, err error) error {
+	return kerrors.NewForbidden(schema.GroupResource{Group: tacomoev1beta1.GroupVersion.Group, Resource: v.Resource}, obj.GetName(), err)
+}
+
+// SetupWebhooksWithManager serves the CatFact and ClusterCatFact validating
+// webhooks. CatFacts are counted for quotas from informers, which must hold
+// every CatFact in the watched namespaces. Empty namespaces means all.
+func SetupWebhooksWithManager(ctx context.Context, mgr ctrl.Manager, informers cache.Informers, namespaces []string) error {
+	quota, err := NewQuotaTracker(ctx, informers, namespaces)
+	if err != nil {
+		return err
+	}
+	if err := ctrl.NewWebhookManagedBy(mgr, &tacomoev1beta1.CatFact{}).
+		WithValidator(&Validator[*tacomoev1beta1.CatFact]{Client: mgr.GetClient(), Resource: "catfacts", Quota: quota}).
+		Complete(); err != nil {
+		return err
+	}
+	return ctrl.NewWebhookManagedBy(mgr, &tacomoev1beta1.ClusterCatFact{}).
+		WithValidator(&Validator[*tacomoev1beta1.ClusterCatFact]{Client: mgr.GetClient(), Resource: "clustercatfacts"}).
+		Complete()
+}
@@ -0,0 +1,148 @@
+package admission
+
+import (
+	"context"
+	"testing"
+
+	admissionv1 "k8s.io/api/admission/v1"
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Return a Validator whose client only lets the user "curator" unlock
+// CatFacts
+func newTestValidator() *Validator[*tacomoev1beta1.CatFact] {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	tacomoev1beta1.AddToScheme(scheme)
+
+	kclient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				if review, ok := obj.(*authorizationv1.SubjectAccessReview); ok {
+					attrs := review.Spec.ResourceAttributes
+					review.Status.Allowed = review.Spec.User == "curator" &&
+						attrs.Verb == UnlockVerb &&
+						attrs.Resource == "catfacts" &&
+						attrs.Name == "curated"
+					return nil
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+	return &Validator[*tacomoev1beta1.CatFact]{Client: kclient, Resource: "catfacts"}
+}
+
+// Return a context for a request made by username
+func requestBy(username string) context.Context {
+	return admission.NewContextWithRequest(context.Background(), admission.Request{
+		AdmissionRequest: admissionv1.AdmissionRequest{
+			UserInfo: authenticationv1.UserInfo{Username: username},
+		},
+	})
+}
+
+func newLockedCatFact() *tacomoev1beta1.CatFact {
+	return &tacomoev1beta1.CatFact{
+		ObjectMeta: metav1.ObjectMeta{Name: "curated", Namespace: "default"},
+		Spec:       tacomoev1beta1.CatFactSpec{Fact: "Cats are cool!", IconName: "Joy", Locked: true},
+	}
+}
+
+func TestValidateUpdateLocked(t *testing.T) {
+	validator := newTestValidator()
+	ctx := requestBy("kitten")
+
+	tests := map[string]func(*tacomoev1beta1.CatFact){
+		"fact":     func(c *tacomoev1beta1.CatFact) { c.Spec.Fact = "Cats sleep a lot" },
+		"iconName": func(c *tacomoev1beta1.CatFact) { c.Spec.IconName = "Evil" },
+		"unlock":   func(c *tacomoev1beta1.CatFact) { c.Spec.Locked = false },
+		"unlock and change": func(c *tacomoev1beta1.CatFact) {
+			c.Spec.Locked = false
+			c.Spec.Fact = "Cats sleep a lot"
+		},
+	}
+	for name, change := range tests {
+		updated := newLockedCatFact()
+		change(updated)
+		if _, err := validator.ValidateUpdate(ctx, newLockedCatFact(), updated); !kerrors.IsForbidden(err) {
+			t.Errorf("%s: expected the update to be forbidden, got %v", name, err)
+		}
+	}
+
+	// Other fields can change while locked
+	updated := newLockedCatFact()
+	updated.Spec.Tags = []string{"trivia"}
+	if _, err := validator.ValidateUpdate(ctx, newLockedCatFact(), updated); err != nil {
+		t.Errorf("Expected tags to be changeable while locked, got %v", err)
+	}
+
+	// Unlocked CatFacts can be changed and locked
+	unlocked := newLockedCatFact()
+	unlocked.Spec.Locked = false
+	updated = newLockedCatFact()
+	updated.Spec.Fact = "Cats sleep a lot"
+	if _, err := validator.ValidateUpdate(ctx, unlocked, updated); err != nil {
+		t.Errorf("Expected an unlocked CatFact to be changeable, got %v", err)
+	}
+}
+
+func TestValidateUpdateUnlockVerb(t *testing.T) {
+	validator := newTestValidator()
+	updated := newLockedCatFact()
+	updated.Spec.Locked = false
+
+	if _, err := validator.ValidateUpdate(requestBy("curator"), newLockedCatFact(), updated); err != nil {
+		t.Errorf("Expected a user with the unlock verb to unlock, got %v", err)
+	}
+	if _, err := validator.ValidateUpdate(requestBy("kitten"), newLockedCatFact(), updated); !kerrors.IsForbidden(err) {
+		t.Errorf("Expected a user without the unlock verb to be forbidden, got %v", err)
+	}
+}
+
+func TestValidateUpdateRollback(t *testing.T) {
+	validator := newTestValidator()
+	ctx := requestBy("kitten")
+	old := newLockedCatFact()
+	old.Spec.Locked = false
+	old.Status.Revision = 3
+	old.Status.History = []tacomoev1beta1.FactRevision{{Revision: 2}, {Revision: 1}}
+
+	tests := map[string]bool{
+		"1":   true,
+		"3":   true,
+		"4":   false,
+		"one": false,
+	}
+	for revision, valid := range tests {
+		updated := old.DeepCopy()
+		updated.Annotations = map[string]string{tacomoev1beta1.RollbackAnnotation: revision}
+		_, err := validator.ValidateUpdate(ctx, old, updated)
+		if valid && err != nil {
+			t.Errorf("%s: expected the rollback to be allowed, got %v", revision, err)
+		}
+		if !valid && !kerrors.IsBadRequest(err) {
+			t.Errorf("%s: expected the rollback to be rejected, got %v", revision, err)
+		}
+	}
+
+	locked := old.DeepCopy()
+	locked.Spec.Locked = true
+	updated := locked.DeepCopy()
+	updated.Annotations = map[string]string{tacomoev1beta1.RollbackAnnotation: "1"}
+	if _, err := validator.ValidateUpdate(ctx, locked, updated); !kerrors.IsForbidden(err) {
+		t.Errorf("Expected rolling back a locked CatFact to be forbidden, got %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+/*
+Authentication and authorization for the operator backend API.
+*/
+
+package backend
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+
+	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+)
+
+type userKey struct{}
+
+// Return the user authenticated by authenticate
+func userFrom(ctx context.Context) (authenticationv1.UserInfo, bool) {
+	user, ok := ctx.Value(userKey{}).(authenticationv1.UserInfo)
+	return user, ok
+}
+
+// Authenticate the bearer token on every request with a TokenReview
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			writeError(w, http.StatusUnauthorized, "missing bearer token")
+			return
+		}
+
+		review := &authenticationv1.TokenReview{
+			Spec: authenticationv1.TokenReviewSpec{Token: token},
+		}
+		if err := s.Client.Create(r.Context(), review); err != nil {
+			backendLog.Error(err, "unable to review token")
+			writeError(w, http.StatusInternalServerError, "unable to authenticate request")
+			return
+		}
+		if !review.Status.Authenticated {
+			writeError(w, http.StatusUnauthorized, "invalid bearer token")
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userKey{}, review.Status.User)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Return true if the authenticated user may perform verb on a CatFact
+// resource in namespace
+func (s *Server) authorize(r *http.Request, verb string, resource string, namespace string) (bool, error) {
+	user, ok := userFrom(r.Context())
+	if !ok {
+		return false, nil
+	}
+
+	extra := map[string]authorizationv1.ExtraValue{}
+	for k, v := range user.Extra {
+		extra[k] = authorizationv1.ExtraValue(v)
+	}
+	review := &authorizationv1.SubjectAccessReview{
+		Spec: authorizationv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			UID:    user.UID,
+			Groups: user.Groups,
+			Extra:  extra,
+			ResourceAttributes: &authorizationv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Group:     tacomoev1alpha1.GroupVersion.Group,
+				Resource:  resource,
+			},
+		},
+	}
+	if err := s.Client.Create(r.Context(), review); err != nil {
+		return false, err
+	}
+	return review.Status.Allowed, nil
+}
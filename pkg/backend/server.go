@@ -0,0 +1,522 @@
+/*
+HTTP API served by the operator for the OpenShift Dynamic Console plugin.
+
+The plugin reaches this API through the console backend proxy registered on
+the ConsolePlugin:
+
+	/api/proxy/plugin/cat-facts-operator-console-plugin/backend/<path>
+
+The console forwards the logged-in user's token (UserToken authorization).
+Every request is authenticated with a TokenReview, and namespace scoped
+requests are authorized with a SubjectAccessReview, so users only see what
+they could already see through the Kubernetes API.
+*/
+
+package backend
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/certwatcher"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+)
+
+var backendLog = ctrl.Log.WithName("backend")
+
+// RBAC verb on catfacts needed to vote. Votes are written with the operator's
+// permissions, so users don't need access to catfactvotes, where they could
+// vote as someone else.
+const VoteVerb = "vote"
+
+// How long a provider health check result is reused
+const healthCacheTTL = 30 * time.Second
+
+// Random facts fetched from the fact source per second across all users, and
+// the burst allowed. Requests over the limit get the last fetched fact.
+const (
+	randomFactQPS   = 1
+	randomFactBurst = 5
+)
+
+// +kubebuilder:rbac:groups=authentication.k8s.io,resources=tokenreviews,verbs=create
+// +kubebuilder:rbac:groups=authorization.k8s.io,resources=subjectaccessreviews,verbs=create
+// +kubebuilder:rbac:groups=ryanmillerc.github.io,resources=catfactvotes,verbs=get;create;update;delete
+
+// Server is the operator backend API. It implements manager.Runnable so it
+// can be added to the controller manager.
+type Server struct {
+	// Client used for TokenReviews, SubjectAccessReviews, reading
+	// CatFacts, and writing CatFactVotes.
+	Client client.Client
+
+	// Address to listen on, e.g. ":9444".
+	BindAddress string
+
+	// Directory containing tls.crt and tls.key. If the files don't exist the
+	// server listens on plain HTTP, which is only useful for local
+	// development.
+	CertDir string
+
+	// Returns the providers to report health for. The first is used for
+	// random facts. Called on every request so reloaded providers are used.
+	Providers func() []core.FactProvider
+
+	// Namespaces the operator watches. CatFacts in other namespaces aren't
+	// cached, so stats for them are not found. Empty means all namespaces.
+	Namespaces []string
+
+	// Set if Client reads CatFacts from a cache with the TagsField and
+	// CategoryField indexes. Otherwise CatFacts are filtered after listing.
+	IndexedCatFacts bool
+
+	healthMu      sync.Mutex
+	healthChecked time.Time
+	health        []ProviderHealth
+
+	randomMu      sync.Mutex
+	randomLimiter *rate.Limiter
+	randomFact    *RandomFactResponse
+}
+
+// Start serves the API until ctx is cancelled.
+func (s *Server) Start(ctx context.Context) error {
+	srv := &http.Server{
+		Addr:              s.BindAddress,
+		Handler:           s.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	certPath := filepath.Join(s.CertDir, "tls.crt")
+	keyPath := filepath.Join(s.CertDir, "tls.key")
+	useTLS := fileExists(certPath) && fileExists(keyPath)
+	if useTLS {
+		watcher, err := certwatcher.New(certPath, keyPath)
+		if err != nil {
+			return err
+		}
+		go func() {
+			if err := watcher.Start(ctx); err != nil {
+				backendLog.Error(err, "certificate watcher stopped")
+			}
+		}()
+		srv.TLSConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: watcher.GetCertificate,
+		}
+	} else {
+		backendLog.Info("No serving certificate found, serving plain HTTP", "certDir", s.CertDir)
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		backendLog.Info("Starting backend API server", "address", s.BindAddress, "tls", useTLS)
+		var err error
+		if useTLS {
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if !errors.Is(err, http.ErrServerClosed) {
+			errCh <- err
+		}
+		close(errCh)
+	}()
+
+	select {
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	case err := <-errCh:
+		return err
+	}
+}
+
+// NeedLeaderElection returns false so every replica serves the API.
+func (s *Server) NeedLeaderElection() bool {
+	return false
+}
+
+// Handler returns the API routes wrapped in authentication.
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/v1/info", s.handleInfo)
+	mux.HandleFunc("GET /api/v1/icons", s.handleIcons)
+	mux.HandleFunc("GET /api/v1/facts/random", s.handleRandomFact)
+	mux.HandleFunc("GET /api/v1/providers/health", s.handleProviderHealth)
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/stats", s.handleNamespaceStats)
+	mux.HandleFunc("GET /api/v1/namespaces/{namespace}/facts", s.handleNamespaceFacts)
+	mux.HandleFunc("PUT /api/v1/namespaces/{namespace}/catfacts/{name}/vote", s.handleVote)
+	mux.HandleFunc("DELETE /api/v1/namespaces/{namespace}/catfacts/{name}/vote", s.handleRetractVote)
+	return s.authenticate(mux)
+}
+
+// InfoResponse is returned by GET /api/v1/info
+type InfoResponse struct {
+	Version string `json:"version"`
+
+	// Namespaces watched by the operator. Empty means all namespaces.
+	Namespaces []string `json:"namespaces"`
+
+	FeatureGates []features.FeatureStatus `json:"featureGates"`
+}
+
+func (s *Server) handleInfo(w http.ResponseWriter, r *http.Request) {
+	namespaces := s.Namespaces
+	if namespaces == nil {
+		namespaces = []string{}
+	}
+	writeJSON(w, http.StatusOK, InfoResponse{
+		Version:      config.Version,
+		Namespaces:   namespaces,
+		FeatureGates: features.DefaultFeatureGate.Status(),
+	})
+}
+
+// IconsResponse is returned by GET /api/v1/icons with the allowed icon names
+type IconsResponse struct {
+	Icons []string `json:"icons"`
+}
+
+func (s *Server) handleIcons(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, IconsResponse{Icons: core.AllowedIconNames()})
+}
+
+// RandomFactResponse is returned by GET /api/v1/facts/random
+type RandomFactResponse struct {
+	Fact     string `json:"fact"`
+	IconName string `json:"iconName"`
+	Provider string `json:"provider"`
+}
+
+func (s *Server) handleRandomFact(w http.ResponseWriter, r *http.Request) {
+	providers := s.providers()
+	if len(providers) == 0 {
+		writeError(w, http.StatusServiceUnavailable, "no fact providers configured")
+		return
+	}
+	provider := providers[0]
+	allowed, last := s.allowRandomFact()
+	if !allowed {
+		if last == nil {
+			writeError(w, http.StatusTooManyRequests, "too many requests for random facts, try again shortly")
+			return
+		}
+		writeJSON(w, http.StatusOK, RandomFactResponse{Fact: last.Fact, IconName: core.RandomIconName(), Provider: last.Provider})
+		return
+	}
+	fact, err := provider.Fact(r.Context())
+	if err != nil {
+		backendLog.Error(err, "unable to get fact", "provider", provider.Name())
+		writeError(w, http.StatusBadGateway, "unable to get fact from "+provider.Name())
+		return
+	}
+	response := RandomFactResponse{
+		Fact:     fact,
+		IconName: core.RandomIconName(),
+		Provider: provider.Name(),
+	}
+	s.randomMu.Lock()
+	s.randomFact = &response
+	s.randomMu.Unlock()
+	writeJSON(w, http.StatusOK, response)
+}
+
+// Return true if a random fact may be fetched from the fact source, so the
+// console can't be used to hammer external APIs. Also returns the last
+// fetched fact, reused when fetching isn't allowed.
+func (s *Server) allowRandomFact() (bool, *RandomFactResponse) {
+	s.randomMu.Lock()
+	defer s.randomMu.Unlock()
+	if s.randomLimiter == nil {
+		s.randomLimiter = rate.NewLimiter(randomFactQPS, randomFactBurst)
+	}
+	return s.randomLimiter.Allow(), s.randomFact
+}
+
+// ProviderHealth is the health of a single fact provider
+type ProviderHealth struct {
+	Name    string `json:"name"`
+	Healthy bool   `json:"healthy"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ProviderHealthResponse is returned by GET /api/v1/providers/health
+type ProviderHealthResponse struct {
+	Providers []ProviderHealth `json:"providers"`
+	CheckedAt time.Time        `json:"checkedAt"`
+}
+
+func (s *Server) handleProviderHealth(w http.ResponseWriter, r *http.Request) {
+	health, checkedAt := s.checkProviders(r.Context())
+	writeJSON(w, http.StatusOK, ProviderHealthResponse{Providers: health, CheckedAt: checkedAt})
+}
+
+// Check every provider. Results are cached for healthCacheTTL so the console
+// can't be used to hammer external APIs.
+func (s *Server) checkProviders(ctx context.Context) ([]ProviderHealth, time.Time) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	if s.health != nil && time.Since(s.healthChecked) < healthCacheTTL {
+		return s.health, s.healthChecked
+	}
+
+	providers := s.providers()
+	health := make([]ProviderHealth, 0, len(providers))
+	for _, provider := range providers {
+		h := ProviderHealth{Name: provider.Name(), Healthy: true}
+		if _, err := provider.Fact(ctx); err != nil {
+			h.Healthy = false
+			h.Error = err.Error()
+		}
+		health = append(health, h)
+	}
+	s.health = health
+	s.healthChecked = time.Now()
+	return s.health, s.healthChecked
+}
+
+// NamespaceStatsResponse is returned by GET /api/v1/namespaces/{namespace}/stats
+type NamespaceStatsResponse struct {
+	Namespace string         `json:"namespace"`
+	Total     int            `json:"total"`
+	ByIcon    map[string]int `json:"byIcon"`
+}
+
+func (s *Server) handleNamespaceStats(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	if !s.authorizeResource(w, r, "list", "catfacts", namespace) {
+		return
+	}
+
+	var catFacts tacomoev1beta1.CatFactList
+	if err := s.Client.List(r.Context(), &catFacts, client.InNamespace(namespace)); err != nil {
+		backendLog.Error(err, "unable to list catfacts", "namespace", namespace)
+		writeError(w, http.StatusInternalServerError, "unable to list catfacts")
+		return
+	}
+
+	stats := NamespaceStatsResponse{
+		Namespace: namespace,
+		Total:     len(catFacts.Items),
+		ByIcon:    map[string]int{},
+	}
+	for _, catFact := range catFacts.Items {
+		stats.ByIcon[catFact.Status.IconName]++
+	}
+	writeJSON(w, http.StatusOK, stats)
+}
+
+// NamespaceFactsResponse is returned by
+// GET /api/v1/namespaces/{namespace}/facts?tag=<tag>&category=<category>
+type NamespaceFactsResponse struct {
+	Namespace string        `json:"namespace"`
+	Facts     []FactSummary `json:"facts"`
+}
+
+// FactSummary is a CatFact's resolved fact and how it's classified
+type FactSummary struct {
+	Name     string   `json:"name"`
+	Fact     string   `json:"fact"`
+	IconName string   `json:"iconName"`
+	Category string   `json:"category,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+	Rating   int32    `json:"rating,omitempty"`
+}
+
+func (s *Server) handleNamespaceFacts(w http.ResponseWriter, r *http.Request) {
+	namespace := r.PathValue("namespace")
+	if !s.authorizeResource(w, r, "list", "catfacts", namespace) {
+		return
+	}
+	tag := r.URL.Query().Get("tag")
+	category := r.URL.Query().Get("category")
+
+	// The cache can only be queried by one field at a time. The other one,
+	// if any, is filtered below.
+	opts := []client.ListOption{client.InNamespace(namespace)}
+	if s.IndexedCatFacts && tag != "" {
+		opts = append(opts, client.MatchingFields{tacomoev1beta1.TagsField: tag})
+	} else if s.IndexedCatFacts && category != "" {
+		opts = append(opts, client.MatchingFields{tacomoev1beta1.CategoryField: category})
+	}
+	var catFacts tacomoev1beta1.CatFactList
+	if err := s.Client.List(r.Context(), &catFacts, opts...); err != nil {
+		backendLog.Error(err, "unable to list catfacts", "namespace", namespace)
+		writeError(w, http.StatusInternalServerError, "unable to list catfacts")
+		return
+	}
+
+	response := NamespaceFactsResponse{Namespace: namespace, Facts: []FactSummary{}}
+	for _, catFact := range catFacts.Items {
+		if tag != "" && !slices.Contains(catFact.Spec.Tags, tag) {
+			continue
+		}
+		if category != "" && catFact.Status.Category != category {
+			continue
+		}
+		response.Facts = append(response.Facts, FactSummary{
+			Name:     catFact.Name,
+			Fact:     catFact.Status.Fact,
+			IconName: catFact.Status.IconName,
+			Category: catFact.Status.Category,
+			Tags:     catFact.Spec.Tags,
+			Rating:   catFact.Status.Rating,
+		})
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// VoteRequest is the body of
+// PUT /api/v1/namespaces/{namespace}/catfacts/{name}/vote
+type VoteRequest struct {
+	// 1 for an upvote, -1 for a downvote
+	Value int32 `json:"value"`
+}
+
+// VoteResponse is returned by PUT and DELETE
+// /api/v1/namespaces/{namespace}/catfacts/{name}/vote
+type VoteResponse struct {
+	Namespace string `json:"namespace"`
+	CatFact   string `json:"catFact"`
+	User      string `json:"user"`
+
+	// The user's vote, 0 once it's retracted
+	Value int32 `json:"value"`
+}
+
+// Record the user's vote on a CatFact as a CatFactVote named after the user,
+// so voting again replaces their vote. Votes are owned by the CatFact and
+// deleted with it. The CatFact controller aggregates them into its rating.
+func (s *Server) handleVote(w http.ResponseWriter, r *http.Request) {
+	namespace, name := r.PathValue("namespace"), r.PathValue("name")
+	if !s.authorizeResource(w, r, "get", "catfacts", namespace) || !s.authorizeResource(w, r, VoteVerb, "catfacts", namespace) {
+		return
+	}
+	var body VoteRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || (body.Value != 1 && body.Value != -1) {
+		writeError(w, http.StatusBadRequest, "body must be {\"value\": 1} or {\"value\": -1}")
+		return
+	}
+
+	catFact := &tacomoev1beta1.CatFact{}
+	if err := s.Client.Get(r.Context(), client.ObjectKey{Namespace: namespace, Name: name}, catFact); err != nil {
+		if kerrors.IsNotFound(err) {
+			writeError(w, http.StatusNotFound, "catfact "+name+" not found")
+			return
+		}
+		backendLog.Error(err, "unable to get catfact", "namespace", namespace, "name", name)
+		writeError(w, http.StatusInternalServerError, "unable to get catfact")
+		return
+	}
+
+	user, _ := userFrom(r.Context())
+	vote := &tacomoev1beta1.CatFactVote{ObjectMeta: metav1.ObjectMeta{
+		Name:      tacomoev1beta1.VoteName(name, user.Username),
+		Namespace: namespace,
+	}}
+	_, err := controllerutil.CreateOrUpdate(r.Context(), s.Client, vote, func() error {
+		vote.Spec = tacomoev1beta1.CatFactVoteSpec{CatFactName: name, User: user.Username, Value: body.Value}
+		return controllerutil.SetOwnerReference(catFact, vote, s.Client.Scheme())
+	})
+	if kerrors.IsConflict(err) || kerrors.IsAlreadyExists(err) {
+		writeError(w, http.StatusConflict, "vote changed while it was being recorded, try again")
+		return
+	}
+	if err != nil {
+		backendLog.Error(err, "unable to record vote", "namespace", namespace, "name", name)
+		writeError(w, http.StatusInternalServerError, "unable to record vote")
+		return
+	}
+	writeJSON(w, http.StatusOK, VoteResponse{Namespace: namespace, CatFact: name, User: user.Username, Value: body.Value})
+}
+
+// Delete the user's vote on a CatFact, if they voted
+func (s *Server) handleRetractVote(w http.ResponseWriter, r *http.Request) {
+	namespace, name := r.PathValue("namespace"), r.PathValue("name")
+	if !s.authorizeResource(w, r, VoteVerb, "catfacts", namespace) {
+		return
+	}
+	user, _ := userFrom(r.Context())
+	vote := &tacomoev1beta1.CatFactVote{ObjectMeta: metav1.ObjectMeta{
+		Name:      tacomoev1beta1.VoteName(name, user.Username),
+		Namespace: namespace,
+	}}
+	if err := s.Client.Delete(r.Context(), vote); client.IgnoreNotFound(err) != nil {
+		backendLog.Error(err, "unable to retract vote", "namespace", namespace, "name", name)
+		writeError(w, http.StatusInternalServerError, "unable to retract vote")
+		return
+	}
+	writeJSON(w, http.StatusOK, VoteResponse{Namespace: namespace, CatFact: name, User: user.Username})
+}
+
+// Check that the operator watches namespace and the user may perform verb on
+// resource, e.g. catfacts, in it. Writes an error response and returns false
+// if not.
+func (s *Server) authorizeResource(w http.ResponseWriter, r *http.Request, verb, resource, namespace string) bool {
+	if len(s.Namespaces) > 0 && !slices.Contains(s.Namespaces, namespace) {
+		writeError(w, http.StatusNotFound, "namespace "+namespace+" is not watched by the operator")
+		return false
+	}
+
+	allowed, err := s.authorize(r, verb, resource, namespace)
+	if err != nil {
+		backendLog.Error(err, "unable to authorize request")
+		writeError(w, http.StatusInternalServerError, "unable to authorize request")
+		return false
+	}
+	if !allowed {
+		writeError(w, http.StatusForbidden, "not allowed to "+verb+" "+resource+" in namespace "+namespace)
+		return false
+	}
+	return true
+}
+
+// Return the configured providers, or none if Providers isn't set
+func (s *Server) providers() []core.FactProvider {
+	if s.Providers == nil {
+		return nil
+	}
+	return s.Providers()
+}
+
+// errorResponse is returned with any non-2xx status
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, errorResponse{Error: message})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		backendLog.Error(err, "unable to write response")
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
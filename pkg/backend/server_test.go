@@ -0,0 +1,346 @@
+package backend
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	authenticationv1 "k8s.io/api/authentication/v1"
+	authorizationv1 "k8s.io/api/authorization/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+)
+
+type fakeProvider struct {
+	fact  string
+	err   error
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Fact(context.Context) (string, error) {
+	p.calls++
+	return p.fact, p.err
+}
+
+// Return a Server whose client accepts the tokens "valid" and "reader" and
+// only allows access to the "allowed" namespace. Only "valid" may vote.
+func newTestServer(providers ...*fakeProvider) *Server {
+	scheme := runtime.NewScheme()
+	clientgoscheme.AddToScheme(scheme)
+	tacomoev1beta1.AddToScheme(scheme)
+
+	catFacts := []client.Object{
+		&tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{Name: "one", Namespace: "allowed"},
+			Spec:       tacomoev1beta1.CatFactSpec{Tags: []string{"trivia", "kids"}},
+			Status:     tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", IconName: "Joy", Category: "general"},
+		},
+		&tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{Name: "two", Namespace: "allowed"},
+			Spec:       tacomoev1beta1.CatFactSpec{Tags: []string{"trivia"}},
+			Status:     tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", IconName: "Joy", Category: "history"},
+		},
+		&tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{Name: "three", Namespace: "allowed"},
+			Status:     tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", IconName: "Evil"},
+		},
+	}
+
+	kclient := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(catFacts...).
+		WithIndex(&tacomoev1beta1.CatFact{}, tacomoev1beta1.TagsField, func(obj client.Object) []string {
+			return obj.(*tacomoev1beta1.CatFact).Spec.Tags
+		}).
+		WithIndex(&tacomoev1beta1.CatFact{}, tacomoev1beta1.CategoryField, func(obj client.Object) []string {
+			return []string{obj.(*tacomoev1beta1.CatFact).Status.Category}
+		}).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Create: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.CreateOption) error {
+				switch review := obj.(type) {
+				case *authenticationv1.TokenReview:
+					switch review.Spec.Token {
+					case "valid":
+						review.Status.Authenticated = true
+						review.Status.User = authenticationv1.UserInfo{Username: "kitten"}
+					case "reader":
+						review.Status.Authenticated = true
+						review.Status.User = authenticationv1.UserInfo{Username: "reader"}
+					}
+					return nil
+				case *authorizationv1.SubjectAccessReview:
+					attrs := review.Spec.ResourceAttributes
+					reads := (attrs.Verb == "list" || attrs.Verb == "get") && attrs.Resource == "catfacts"
+					votes := attrs.Verb == VoteVerb && attrs.Resource == "catfacts"
+					review.Status.Allowed = attrs.Namespace == "allowed" &&
+						(reads && (review.Spec.User == "kitten" || review.Spec.User == "reader") ||
+							votes && review.Spec.User == "kitten")
+					return nil
+				}
+				return c.Create(ctx, obj, opts...)
+			},
+		}).
+		Build()
+
+	factProviders := []core.FactProvider{}
+	for _, p := range providers {
+		factProviders = append(factProviders, p)
+	}
+	return &Server{
+		Client:    kclient,
+		Providers: func() []core.FactProvider { return factProviders },
+	}
+}
+
+// Send a GET request with token and decode the JSON response into out
+func get(t *testing.T, server *Server, path string, token string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("Unable to decode response from %s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestAuthentication(t *testing.T) {
+	server := newTestServer()
+	if code := get(t, server, "/api/v1/icons", "", nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a token, got %d", code)
+	}
+	if code := get(t, server, "/api/v1/icons", "invalid", nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with an invalid token, got %d", code)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	var info InfoResponse
+	if code := get(t, newTestServer(), "/api/v1/info", "valid", &info); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if info.Version == "" || len(info.FeatureGates) == 0 {
+		t.Errorf("Unexpected info %+v", info)
+	}
+}
+
+func TestIcons(t *testing.T) {
+	var icons IconsResponse
+	if code := get(t, newTestServer(), "/api/v1/icons", "valid", &icons); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(icons.Icons) != 9 {
+		t.Errorf("Expected 9 icons, got %v", icons.Icons)
+	}
+}
+
+func TestRandomFact(t *testing.T) {
+	var fact RandomFactResponse
+	server := newTestServer(&fakeProvider{fact: "Cats are cool!"})
+	if code := get(t, server, "/api/v1/facts/random", "valid", &fact); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if fact.Fact != "Cats are cool!" || fact.Provider != "fake" || fact.IconName == "" {
+		t.Errorf("Unexpected random fact %+v", fact)
+	}
+
+	server = newTestServer(&fakeProvider{err: errors.New("down")})
+	if code := get(t, server, "/api/v1/facts/random", "valid", nil); code != http.StatusBadGateway {
+		t.Errorf("Expected 502 when the provider fails, got %d", code)
+	}
+}
+
+func TestRandomFactRateLimit(t *testing.T) {
+	provider := &fakeProvider{fact: "Cats are cool!"}
+	server := newTestServer(provider)
+	for i := 0; i < 20; i++ {
+		var fact RandomFactResponse
+		if code := get(t, server, "/api/v1/facts/random", "valid", &fact); code != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", code)
+		}
+		if fact.Fact != "Cats are cool!" {
+			t.Errorf("Expected the last fact when rate limited, got %+v", fact)
+		}
+	}
+	if provider.calls > randomFactBurst+1 {
+		t.Errorf("Expected at most %d requests to the provider, got %d", randomFactBurst+1, provider.calls)
+	}
+
+	provider = &fakeProvider{err: errors.New("down")}
+	server = newTestServer(provider)
+	for i := 0; i < randomFactBurst; i++ {
+		get(t, server, "/api/v1/facts/random", "valid", nil)
+	}
+	if code := get(t, server, "/api/v1/facts/random", "valid", nil); code != http.StatusTooManyRequests {
+		t.Errorf("Expected 429 when rate limited without a fact, got %d", code)
+	}
+}
+
+func TestProviderHealth(t *testing.T) {
+	var health ProviderHealthResponse
+	server := newTestServer(&fakeProvider{fact: "Cats are cool!"}, &fakeProvider{err: errors.New("down")})
+	if code := get(t, server, "/api/v1/providers/health", "valid", &health); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if len(health.Providers) != 2 || !health.Providers[0].Healthy || health.Providers[1].Healthy {
+		t.Errorf("Unexpected provider health %+v", health.Providers)
+	}
+}
+
+func TestNamespaceStats(t *testing.T) {
+	var stats NamespaceStatsResponse
+	server := newTestServer()
+	if code := get(t, server, "/api/v1/namespaces/allowed/stats", "valid", &stats); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if stats.Total != 3 || stats.ByIcon["Joy"] != 2 || stats.ByIcon["Evil"] != 1 {
+		t.Errorf("Unexpected namespace stats %+v", stats)
+	}
+
+	if code := get(t, server, "/api/v1/namespaces/denied/stats", "valid", nil); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a namespace the user can't list, got %d", code)
+	}
+}
+
+func TestNamespaceStatsUnwatched(t *testing.T) {
+	server := newTestServer()
+	server.Namespaces = []string{"other"}
+	if code := get(t, server, "/api/v1/namespaces/allowed/stats", "valid", nil); code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a namespace that isn't watched, got %d", code)
+	}
+}
+
+func TestNamespaceFacts(t *testing.T) {
+	for _, indexed := range []bool{true, false} {
+		server := newTestServer()
+		server.IndexedCatFacts = indexed
+
+		tests := map[string][]string{
+			"":                             {"one", "three", "two"},
+			"?tag=trivia":                  {"one", "two"},
+			"?category=history":            {"two"},
+			"?tag=trivia&category=general": {"one"},
+			"?tag=kids&category=history":   {},
+			"?category=anatomy":            {},
+		}
+		for query, want := range tests {
+			var facts NamespaceFactsResponse
+			if code := get(t, server, "/api/v1/namespaces/allowed/facts"+query, "valid", &facts); code != http.StatusOK {
+				t.Fatalf("%s: expected 200, got %d", query, code)
+			}
+			names := []string{}
+			for _, fact := range facts.Facts {
+				names = append(names, fact.Name)
+			}
+			if !slices.Equal(names, want) {
+				t.Errorf("%s (indexed %t): expected facts %v, got %v", query, indexed, want, names)
+			}
+		}
+	}
+
+	server := newTestServer()
+	if code := get(t, server, "/api/v1/namespaces/denied/facts", "valid", nil); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a namespace the user can't list, got %d", code)
+	}
+}
+
+// Send a request with the token "valid" and decode the JSON response into out
+func send(t *testing.T, server *Server, method, path, body string, out interface{}) int {
+	t.Helper()
+	return sendAs(t, server, "valid", method, path, body, out)
+}
+
+// Send a request with token and decode the JSON response into out
+func sendAs(t *testing.T, server *Server, token, method, path, body string, out interface{}) int {
+	t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+	server.Handler().ServeHTTP(rec, req)
+	if out != nil && rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), out); err != nil {
+			t.Fatalf("Unable to decode response from %s: %v", path, err)
+		}
+	}
+	return rec.Code
+}
+
+func TestVote(t *testing.T) {
+	server := newTestServer()
+	path := "/api/v1/namespaces/allowed/catfacts/one/vote"
+	key := client.ObjectKey{Namespace: "allowed", Name: tacomoev1beta1.VoteName("one", "kitten")}
+
+	var response VoteResponse
+	if code := send(t, server, http.MethodPut, path, `{"value":1}`, &response); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if response.User != "kitten" || response.Value != 1 {
+		t.Errorf("Unexpected vote response %+v", response)
+	}
+	vote := &tacomoev1beta1.CatFactVote{}
+	if err := server.Client.Get(context.Background(), key, vote); err != nil {
+		t.Fatalf("Expected the vote to be recorded: %v", err)
+	}
+	if vote.Spec.CatFactName != "one" || vote.Spec.User != "kitten" || len(vote.OwnerReferences) != 1 {
+		t.Errorf("Unexpected vote %+v", vote)
+	}
+
+	// Voting again replaces the vote
+	if code := send(t, server, http.MethodPut, path, `{"value":-1}`, nil); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	var votes tacomoev1beta1.CatFactVoteList
+	server.Client.List(context.Background(), &votes)
+	if len(votes.Items) != 1 || votes.Items[0].Spec.Value != -1 {
+		t.Errorf("Expected one downvote, got %+v", votes.Items)
+	}
+
+	if code := send(t, server, http.MethodDelete, path, "", nil); code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d", code)
+	}
+	if err := server.Client.Get(context.Background(), key, vote); !kerrors.IsNotFound(err) {
+		t.Errorf("Expected the vote to be retracted, got %v", err)
+	}
+
+	tests := map[string]struct {
+		path, body string
+		want       int
+	}{
+		"invalid value": {path, `{"value":2}`, http.StatusBadRequest},
+		"missing":       {"/api/v1/namespaces/allowed/catfacts/missing/vote", `{"value":1}`, http.StatusNotFound},
+		"denied":        {"/api/v1/namespaces/denied/catfacts/one/vote", `{"value":1}`, http.StatusForbidden},
+	}
+	for name, test := range tests {
+		if code := send(t, server, http.MethodPut, test.path, test.body, nil); code != test.want {
+			t.Errorf("%s: expected %d, got %d", name, test.want, code)
+		}
+	}
+
+	// Reading CatFacts isn't enough to vote
+	if code := sendAs(t, server, "reader", http.MethodPut, path, `{"value":1}`, nil); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user without the vote verb, got %d", code)
+	}
+	if code := sendAs(t, server, "reader", http.MethodDelete, path, "", nil); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a user without the vote verb, got %d", code)
+	}
+}
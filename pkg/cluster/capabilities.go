@@ -0,0 +1,151 @@
+/*
+Detect what the cluster the operator is running on is capable of.
+
+The operator should run on plain Kubernetes (kind, minikube, etc.) as well as
+OpenShift. Rather than assuming OpenShift and erroring when it isn't, API
+discovery is used to find out which optional APIs are served. The result is a
+ClusterCapabilities value that the rest of the operator consults before doing
+anything OpenShift specific.
+*/
+
+package cluster
+
+import (
+	"context"
+
+	configv1 "github.com/openshift/api/config/v1"
+	consolev1 "github.com/openshift/api/console/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
+)
+
+var clusterLog = ctrl.Log.WithName("cluster")
+
+// +kubebuilder:rbac:groups=config.openshift.io,resources=clusterversions,verbs=get
+
+// ClusterCapabilities describes optional APIs and features available on the
+// cluster the operator is running on.
+type ClusterCapabilities struct {
+	// ClusterVersion is true when config.openshift.io/v1 ClusterVersion is
+	// served. This is the best indicator that the cluster is OpenShift.
+	ClusterVersion bool
+
+	// ConsolePlugin is true when console.openshift.io/v1 ConsolePlugin is
+	// served.
+	ConsolePlugin bool
+
+	// OpenShiftVersion is the version of OpenShift in canonical semver form
+	// (e.g. "v4.19.3"). Empty if the cluster isn't OpenShift or the version
+	// couldn't be read.
+	OpenShiftVersion string
+}
+
+// IsOpenShift returns true if the cluster is an OpenShift cluster.
+func (c ClusterCapabilities) IsOpenShift() bool {
+	return c.ClusterVersion
+}
+
+// SupportsConsolePlugin returns true if the operator should deploy the
+// OpenShift console dynamic plugin.
+//
+// The ConsolePlugin API has to be served. When the OpenShift version is known
+// it also has to meet config.MinConsolePluginOCPVer, because the plugin is
+// built against a PatternFly version only shipped with newer consoles.
+func (c ClusterCapabilities) SupportsConsolePlugin() bool {
+	if !c.ConsolePlugin {
+		return false
+	}
+	if c.OpenShiftVersion == "" {
+		return true
+	}
+	ok, err := VersionAtLeast(c.OpenShiftVersion, config.MinConsolePluginOCPVer)
+	return err == nil && ok
+}
+
+// DetectCapabilities uses API discovery to determine which optional APIs the
+// cluster serves and, on OpenShift, reads the cluster version.
+//
+// An error is only returned if the API server can't be queried. Missing APIs
+// are not errors; they are reported as false capabilities.
+func DetectCapabilities(cfg *rest.Config) (ClusterCapabilities, error) {
+	dclient, err := discovery.NewDiscoveryClientForConfig(cfg)
+	if err != nil {
+		return ClusterCapabilities{}, err
+	}
+	capabilities, err := detectAPIs(dclient)
+	if err != nil {
+		return capabilities, err
+	}
+
+	if capabilities.ClusterVersion {
+		scheme := runtime.NewScheme()
+		if err := configv1.AddToScheme(scheme); err != nil {
+			return capabilities, err
+		}
+		kclient, err := client.New(cfg, client.Options{Scheme: scheme})
+		if err != nil {
+			return capabilities, err
+		}
+		version, err := getOpenShiftVersion(kclient)
+		if err != nil {
+			// Not fatal, SupportsConsolePlugin falls back to API presence
+			clusterLog.Error(err, "unable to read OpenShift version")
+		} else {
+			capabilities.OpenShiftVersion = version
+		}
+	}
+
+	return capabilities, nil
+}
+
+// Populate API capabilities from discovery
+func detectAPIs(dclient discovery.DiscoveryInterface) (ClusterCapabilities, error) {
+	var capabilities ClusterCapabilities
+	var err error
+
+	capabilities.ClusterVersion, err = isResourceServed(dclient, configv1.GroupVersion.String(), "clusterversions")
+	if err != nil {
+		return capabilities, err
+	}
+
+	capabilities.ConsolePlugin, err = isResourceServed(dclient, consolev1.GroupVersion.String(), "consoleplugins")
+	if err != nil {
+		return capabilities, err
+	}
+
+	return capabilities, nil
+}
+
+// Returns true if the API server serves the resource in the group version
+func isResourceServed(dclient discovery.DiscoveryInterface, groupVersion string, resource string) (bool, error) {
+	resources, err := dclient.ServerResourcesForGroupVersion(groupVersion)
+	if err != nil {
+		if kerrors.IsNotFound(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	for _, r := range resources.APIResources {
+		if r.Name == resource {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Return the canonical semantic version of the running OpenShift cluster
+func getOpenShiftVersion(kclient client.Client) (string, error) {
+	var clusterVersion configv1.ClusterVersion
+	err := kclient.Get(context.TODO(), client.ObjectKey{Name: "version"}, &clusterVersion, &client.GetOptions{})
+	if err != nil {
+		return "", err
+	}
+	// Desired version appears to be the current OpenShift cluster version
+	return ParseVersion(clusterVersion.Status.Desired.Version)
+}
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	tests := map[string]string{
+		"4.19.3":                             "v4.19.3",
+		"v4.19.3":                            "v4.19.3",
+		"4.19":                               "v4.19.0",
+		"4.20.0-0.nightly-2025-05-01-000000": "v4.20.0-0.nightly-2025-05-01-000000",
+	}
+	for in, want := range tests {
+		got, err := ParseVersion(in)
+		if err != nil {
+			t.Errorf("ParseVersion(%q) returned error: %v", in, err)
+		}
+		if got != want {
+			t.Errorf("ParseVersion(%q) = %q, want %q", in, got, want)
+		}
+	}
+
+	for _, in := range []string{"", "latest", "4.x"} {
+		if _, err := ParseVersion(in); err == nil {
+			t.Errorf("Expected ParseVersion(%q) to return an error", in)
+		}
+	}
+}
+
+func TestVersionAtLeast(t *testing.T) {
+	tests := []struct {
+		version string
+		want    bool
+	}{
+		{"4.18.9", false},
+		{"4.19.0", true},
+		{"4.19.3", true},
+		{"4.19.0-rc.1", true},
+		{"4.20.0-0.nightly-2025-05-01-000000", true},
+		{"5.0.0", true},
+	}
+	for _, tt := range tests {
+		got, err := VersionAtLeast(tt.version, "4.19")
+		if err != nil {
+			t.Errorf("VersionAtLeast(%q) returned error: %v", tt.version, err)
+		}
+		if got != tt.want {
+			t.Errorf("VersionAtLeast(%q, \"4.19\") = %t, want %t", tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestDetectAPIs(t *testing.T) {
+	dclient := &fakediscovery.FakeDiscovery{Fake: &clienttesting.Fake{}}
+
+	// Vanilla Kubernetes
+	capabilities, err := detectAPIs(dclient)
+	if err != nil {
+		t.Fatalf("detectAPIs returned error: %v", err)
+	}
+	if capabilities.IsOpenShift() || capabilities.SupportsConsolePlugin() {
+		t.Errorf("Expected no OpenShift capabilities, got %+v", capabilities)
+	}
+
+	// OpenShift
+	dclient.Resources = []*metav1.APIResourceList{
+		{
+			GroupVersion: "config.openshift.io/v1",
+			APIResources: []metav1.APIResource{{Name: "clusterversions"}},
+		},
+		{
+			GroupVersion: "console.openshift.io/v1",
+			APIResources: []metav1.APIResource{{Name: "consoleplugins"}},
+		},
+	}
+	capabilities, err = detectAPIs(dclient)
+	if err != nil {
+		t.Fatalf("detectAPIs returned error: %v", err)
+	}
+	if !capabilities.IsOpenShift() || !capabilities.ConsolePlugin {
+		t.Errorf("Expected OpenShift capabilities, got %+v", capabilities)
+	}
+}
+
+func TestSupportsConsolePlugin(t *testing.T) {
+	tests := []struct {
+		capabilities ClusterCapabilities
+		want         bool
+	}{
+		{ClusterCapabilities{}, false},
+		{ClusterCapabilities{ClusterVersion: true, OpenShiftVersion: "v4.19.3"}, false},
+		{ClusterCapabilities{ClusterVersion: true, ConsolePlugin: true, OpenShiftVersion: "v4.18.9"}, false},
+		{ClusterCapabilities{ClusterVersion: true, ConsolePlugin: true, OpenShiftVersion: "v4.19.3"}, true},
+		{ClusterCapabilities{ClusterVersion: true, ConsolePlugin: true}, true},
+	}
+	for _, tt := range tests {
+		if got := tt.capabilities.SupportsConsolePlugin(); got != tt.want {
+			t.Errorf("%+v.SupportsConsolePlugin() = %t, want %t", tt.capabilities, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+/*
+Version parsing for OpenShift versions.
+
+OpenShift reports versions like "4.19.3" or "4.19.0-0.nightly-2025-05-01-000000"
+without the leading "v" golang.org/x/mod/semver expects. These helpers
+normalize versions before comparing them.
+*/
+
+package cluster
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// ParseVersion returns the canonical semver form of an OpenShift version. A
+// leading "v" is optional. Missing minor and patch numbers are filled in with
+// zeros, so "4.19" becomes "v4.19.0".
+func ParseVersion(version string) (string, error) {
+	version = strings.TrimSpace(version)
+	if version == "" {
+		return "", fmt.Errorf("empty version")
+	}
+	if !strings.HasPrefix(version, "v") {
+		version = "v" + version
+	}
+	if !semver.IsValid(version) {
+		return "", fmt.Errorf("not a valid version %s", version)
+	}
+	return semver.Canonical(version), nil
+}
+
+// VersionAtLeast returns true if version is equal to or greater than minimum.
+//
+// Only major and minor numbers are compared. Patch and pre-release parts are
+// ignored so nightly and release candidate builds of a supported minor
+// version are accepted.
+func VersionAtLeast(version string, minimum string) (bool, error) {
+	v, err := ParseVersion(version)
+	if err != nil {
+		return false, err
+	}
+	m, err := ParseVersion(minimum)
+	if err != nil {
+		return false, err
+	}
+	// semver.Compare will return:
+	// 0 if the versions match
+	// 1 if v is greater than m
+	// -1 if v is less than m
+	return semver.Compare(semver.MajorMinor(v), semver.MajorMinor(m)) >= 0, nil
+}
@@ -6,13 +6,31 @@ const (
 	MinConsolePluginOCPVer string = "4.19"
 
 	// Container image path for the console plugin. DO NOT INCLUDE A TAG!
-	// config.Version will be used as the tag.
+	// config.Version will be used as the tag, unless RELATED_IMAGE_CONSOLE_PLUGIN
+	// is set.
 	ConsolePluginImage string = "quay.io/ryanmillerc/cat-facts-operator-console-plugin"
 
+	// Namespace the OpenShift console runs in. The console plugin
+	// NetworkPolicy only allows ingress from this namespace.
+	ConsoleNamespace string = "openshift-console"
+
 	// Name of operator. Console plugin resources will be prefixed with this.
 	OperatorName string = "cat-facts-operator"
 
-	// Version of the operator. This should be a valid semantic version (semver).
-	// TODO: Should allow this to be set from Makefile as an environment variable
-	Version string = "v1.1.2"
+	// Name and port of the Service in front of the operator backend API. The
+	// console plugin reaches the API through a ConsolePlugin proxy to this
+	// Service.
+	BackendServiceName string = "cat-facts-operator-backend"
+	BackendPort        int32  = 9444
+
+	// Name of the operator's own Deployment. It is used as an anchor to clean
+	// up cluster-scoped resources when the operator is uninstalled. Can be
+	// overridden with the OPERATOR_DEPLOYMENT_NAME environment variable.
+	OperatorDeploymentName string = "cat-facts-operator-controller-manager"
 )
+
+// Version of the operator. This should be a valid semantic version (semver).
+// It's set at build time by make from the VERSION make variable:
+//
+//	go build -ldflags "-X github.com/ryanmillerc/cat-facts-operator/pkg/config.Version=v1.2.3"
+var Version = "v0.0.0-dev"
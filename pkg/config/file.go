@@ -0,0 +1,490 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/admission"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/events"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+)
+
+// APIVersion and Kind of the operator config file
+const (
+	ConfigAPIVersion = "config.ryanmillerc.github.io/v1alpha1"
+	ConfigKind       = "OperatorConfig"
+)
+
+// OperatorConfig is the operator config file, usually mounted from a
+// ConfigMap. Flags override values from the file.
+//
+// FactSources, IconPolicy, Translation, Moderation, and Quota are reloaded
+// while the operator runs. Other settings need a restart.
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// Where facts come from. The first source generates facts for CatFacts.
+	FactSources []FactSource `json:"factSources,omitempty"`
+
+	// Limits on Events emitted about CatFacts
+	RateLimits RateLimits `json:"rateLimits,omitempty"`
+
+	// How many CatFacts are reconciled at once
+	Concurrency Concurrency `json:"concurrency,omitempty"`
+
+	// Which icons CatFacts may use
+	IconPolicy IconPolicy `json:"iconPolicy,omitempty"`
+
+	// How facts are translated into CatFacts' spec.locale
+	Translation Translation `json:"translation,omitempty"`
+
+	// Which generated facts may be shown
+	Moderation Moderation `json:"moderation,omitempty"`
+
+	// How many CatFacts namespaces may have
+	Quota Quota `json:"quota,omitempty"`
+
+	// Console plugin Deployment settings
+	ConsolePlugin ConsolePlugin `json:"consolePlugin,omitempty"`
+
+	// Feature gates to enable or disable, by name
+	FeatureGates map[string]bool `json:"featureGates,omitempty"`
+
+	// Where traces are exported
+	Tracing Tracing `json:"tracing,omitempty"`
+}
+
+// FactSource is an API that serves facts in the catfact.ninja format, or a
+// curated library of facts
+type FactSource struct {
+	// Name shown in logs, metrics, and health reports
+	Name string `json:"name"`
+
+	// URL of the fact endpoint. Required unless facts is set.
+	URL string `json:"url,omitempty"`
+
+	// Curated facts to pick from instead of an API. Facts with a higher
+	// rating from CatFactVotes are picked more often.
+	Facts []string `json:"facts,omitempty"`
+
+	// Optional URL of an endpoint serving facts in a category, with
+	// "{category}" replaced by the category CatFacts request in
+	// spec.category. Facts from sources without one are classified by their
+	// text.
+	CategoryURL string `json:"categoryURL,omitempty"`
+}
+
+// RateLimits for the CatFact controller
+type RateLimits struct {
+	// Sustained Events per second, per Event type
+	EventQPS float32 `json:"eventQPS,omitempty"`
+
+	// Events per type that can be emitted in a burst
+	EventBurst int `json:"eventBurst,omitempty"`
+
+	// First delay before a failed CatFact is retried. Doubles on every
+	// failure up to ReconcileMaxDelay.
+	ReconcileBaseDelay metav1.Duration `json:"reconcileBaseDelay,omitempty"`
+
+	// Longest delay before a failed CatFact is retried
+	ReconcileMaxDelay metav1.Duration `json:"reconcileMaxDelay,omitempty"`
+
+	// Sustained retries per second across all CatFacts
+	ReconcileQPS float32 `json:"reconcileQPS,omitempty"`
+
+	// Retries across all CatFacts that can happen in a burst
+	ReconcileBurst int `json:"reconcileBurst,omitempty"`
+}
+
+// Concurrency of the CatFact controller
+type Concurrency struct {
+	// Number of CatFacts reconciled in parallel
+	MaxConcurrentReconciles int `json:"maxConcurrentReconciles,omitempty"`
+
+	// Only reconcile CatFacts when their spec changes, so status, label,
+	// and annotation updates don't cause more work
+	GenerationChangedPredicate bool `json:"generationChangedPredicate,omitempty"`
+}
+
+// IconPolicy restricts the icons CatFacts may use
+type IconPolicy struct {
+	// Icons that are generated for and accepted on CatFacts. Must be a subset
+	// of the icons the console plugin has images for. Empty allows all icons.
+	AllowedIcons []string `json:"allowedIcons,omitempty"`
+}
+
+// Translation of facts into CatFacts' spec.locale. The dictionary is tried
+// first, then url. Facts neither can translate are shown in English.
+type Translation struct {
+	// Translations by locale, then by English fact, e.g. for facts set in
+	// spec.fact. A locale with a region falls back to its language.
+	Dictionary map[string]map[string]string `json:"dictionary,omitempty"`
+
+	// Optional LibreTranslate compatible translate endpoint, e.g.
+	// http://libretranslate:5000/translate
+	URL string `json:"url,omitempty"`
+}
+
+// Moderation of facts fetched from fact sources. Facts set in spec.fact aren't
+// moderated.
+type Moderation struct {
+	// Words and patterns generated facts must not contain
+	Blocklist Blocklist `json:"blocklist,omitempty"`
+
+	// Optional HTTP service asked whether generated facts may be shown
+	Webhook ModerationWebhook `json:"webhook,omitempty"`
+}
+
+// Blocklist of generated facts
+type Blocklist struct {
+	// Words rejected case insensitively, as whole words
+	Words []string `json:"words,omitempty"`
+
+	// Regular expressions (RE2 syntax) facts must not match
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// ModerationWebhook is an HTTP service moderating generated facts. See
+// core.WebhookModerator.
+type ModerationWebhook struct {
+	// URL facts are POSTed to. Disabled when empty.
+	URL string `json:"url,omitempty"`
+
+	// Allow facts when the webhook fails, instead of rejecting them
+	IgnoreFailures bool `json:"ignoreFailures,omitempty"`
+}
+
+// Quota on CatFacts per namespace, enforced by the validating webhook
+type Quota struct {
+	// Most CatFacts a namespace may have, unless its
+	// catfacts.ryanmillerc.github.io/max-catfacts annotation says otherwise.
+	// 0 means no limit.
+	MaxCatFactsPerNamespace int64 `json:"maxCatFactsPerNamespace,omitempty"`
+}
+
+// ConsolePlugin settings. See console.PluginOptions.
+type ConsolePlugin struct {
+	Replicas            int32       `json:"replicas,omitempty"`
+	PodDisruptionBudget bool        `json:"podDisruptionBudget,omitempty"`
+	NetworkPolicy       bool        `json:"networkPolicy,omitempty"`
+	TopologySpread      bool        `json:"topologySpread,omitempty"`
+	Autoscaling         Autoscaling `json:"autoscaling,omitempty"`
+}
+
+// Autoscaling settings for the console plugin
+type Autoscaling struct {
+	Enabled              bool  `json:"enabled,omitempty"`
+	MinReplicas          int32 `json:"minReplicas,omitempty"`
+	MaxReplicas          int32 `json:"maxReplicas,omitempty"`
+	TargetCPUUtilization int32 `json:"targetCPUUtilization,omitempty"`
+}
+
+// Tracing settings. Tracing is disabled unless an endpoint is set here, with
+// a flag, or with OTEL_EXPORTER_OTLP_ENDPOINT.
+type Tracing struct {
+	// OTLP/HTTP traces endpoint, e.g. http://otel-collector:4318/v1/traces
+	Endpoint string `json:"endpoint,omitempty"`
+
+	// Fraction of reconciles that are traced, greater than 0 and up to 1.
+	// Defaults to 1.
+	SampleRatio float64 `json:"sampleRatio,omitempty"`
+}
+
+// Matches the BCP 47 language tags accepted in a CatFact's spec.locale
+var localePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+
+// Defaults for the CatFact controller workqueue rate limiter. These match
+// controller-runtime's defaults.
+const (
+	DefaultReconcileBaseDelay         = 5 * time.Millisecond
+	DefaultReconcileMaxDelay          = 1000 * time.Second
+	DefaultReconcileQPS       float32 = 10
+	DefaultReconcileBurst             = 100
+)
+
+// Return the config used when there is no config file
+func DefaultOperatorConfig() *OperatorConfig {
+	c := &OperatorConfig{}
+	c.SetDefaults()
+	return c
+}
+
+// Fill in unset fields with defaults
+func (c *OperatorConfig) SetDefaults() {
+	if c.APIVersion == "" && c.Kind == "" {
+		c.APIVersion, c.Kind = ConfigAPIVersion, ConfigKind
+	}
+	if len(c.FactSources) == 0 {
+		c.FactSources = []FactSource{{Name: "catfact.ninja", URL: core.DefaultFactURL}}
+	}
+	if c.RateLimits.EventQPS == 0 {
+		c.RateLimits.EventQPS = events.DefaultQPS
+	}
+	if c.RateLimits.EventBurst == 0 {
+		c.RateLimits.EventBurst = events.DefaultBurst
+	}
+	if c.RateLimits.ReconcileBaseDelay.Duration == 0 {
+		c.RateLimits.ReconcileBaseDelay.Duration = DefaultReconcileBaseDelay
+	}
+	if c.RateLimits.ReconcileMaxDelay.Duration == 0 {
+		c.RateLimits.ReconcileMaxDelay.Duration = DefaultReconcileMaxDelay
+	}
+	if c.RateLimits.ReconcileQPS == 0 {
+		c.RateLimits.ReconcileQPS = DefaultReconcileQPS
+	}
+	if c.RateLimits.ReconcileBurst == 0 {
+		c.RateLimits.ReconcileBurst = DefaultReconcileBurst
+	}
+	if c.Concurrency.MaxConcurrentReconciles == 0 {
+		c.Concurrency.MaxConcurrentReconciles = 1
+	}
+	if c.ConsolePlugin.Replicas == 0 {
+		c.ConsolePlugin.Replicas = 1
+	}
+	if c.ConsolePlugin.Autoscaling.MinReplicas == 0 {
+		c.ConsolePlugin.Autoscaling.MinReplicas = 2
+	}
+	if c.ConsolePlugin.Autoscaling.MaxReplicas == 0 {
+		c.ConsolePlugin.Autoscaling.MaxReplicas = 4
+	}
+	if c.ConsolePlugin.Autoscaling.TargetCPUUtilization == 0 {
+		c.ConsolePlugin.Autoscaling.TargetCPUUtilization = 80
+	}
+	if c.Tracing.SampleRatio == 0 {
+		c.Tracing.SampleRatio = 1
+	}
+}
+
+// Return an error describing every invalid field
+func (c *OperatorConfig) Validate() error {
+	var errs []error
+	if c.APIVersion != ConfigAPIVersion || c.Kind != ConfigKind {
+		errs = append(errs, fmt.Errorf("apiVersion and kind must be %s %s, got %s %s", ConfigAPIVersion, ConfigKind, c.APIVersion, c.Kind))
+	}
+
+	names := map[string]bool{}
+	for i, source := range c.FactSources {
+		if source.Name == "" {
+			errs = append(errs, fmt.Errorf("factSources[%d].name is required", i))
+		} else if names[source.Name] {
+			errs = append(errs, fmt.Errorf("factSources[%d].name %q is not unique", i, source.Name))
+		}
+		names[source.Name] = true
+		if len(source.Facts) > 0 {
+			if source.URL != "" || source.CategoryURL != "" {
+				errs = append(errs, fmt.Errorf("factSources[%d] can't have both facts and a url", i))
+			}
+			if slices.Contains(source.Facts, "") {
+				errs = append(errs, fmt.Errorf("factSources[%d].facts can't contain empty facts", i))
+			}
+			continue
+		}
+		u, err := url.Parse(source.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("factSources[%d].url %q must be an absolute http or https URL", i, source.URL))
+		}
+		if source.CategoryURL != "" {
+			u, err := url.Parse(source.CategoryURL)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				errs = append(errs, fmt.Errorf("factSources[%d].categoryURL %q must be an absolute http or https URL", i, source.CategoryURL))
+			}
+		}
+	}
+
+	if c.RateLimits.EventQPS < 0 {
+		errs = append(errs, errors.New("rateLimits.eventQPS must be positive"))
+	}
+	if c.RateLimits.EventBurst < 0 {
+		errs = append(errs, errors.New("rateLimits.eventBurst must be positive"))
+	}
+	if c.RateLimits.ReconcileBaseDelay.Duration < 0 || c.RateLimits.ReconcileMaxDelay.Duration < c.RateLimits.ReconcileBaseDelay.Duration {
+		errs = append(errs, errors.New("rateLimits reconcile delays must be positive with reconcileBaseDelay <= reconcileMaxDelay"))
+	}
+	if c.RateLimits.ReconcileQPS < 0 {
+		errs = append(errs, errors.New("rateLimits.reconcileQPS must be positive"))
+	}
+	if c.RateLimits.ReconcileBurst < 0 {
+		errs = append(errs, errors.New("rateLimits.reconcileBurst must be positive"))
+	}
+	if c.Concurrency.MaxConcurrentReconciles < 0 {
+		errs = append(errs, errors.New("concurrency.maxConcurrentReconciles must be positive"))
+	}
+
+	for _, icon := range c.IconPolicy.AllowedIcons {
+		if !slices.Contains(core.ValidIconNames(), icon) {
+			errs = append(errs, fmt.Errorf("iconPolicy.allowedIcons: %q is not one of %v", icon, core.ValidIconNames()))
+		}
+	}
+
+	for locale := range c.Translation.Dictionary {
+		if !localePattern.MatchString(locale) {
+			errs = append(errs, fmt.Errorf("translation.dictionary: %q is not a BCP 47 language tag", locale))
+		}
+	}
+	if c.Translation.URL != "" {
+		if u, err := url.Parse(c.Translation.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("translation.url %q must be an absolute http or https URL", c.Translation.URL))
+		}
+	}
+
+	if _, err := core.NewBlocklistModerator(c.Moderation.Blocklist.Words, c.Moderation.Blocklist.Patterns); err != nil {
+		errs = append(errs, fmt.Errorf("moderation.blocklist: %w", err))
+	}
+	if c.Moderation.Webhook.URL != "" {
+		if u, err := url.Parse(c.Moderation.Webhook.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("moderation.webhook.url %q must be an absolute http or https URL", c.Moderation.Webhook.URL))
+		}
+	}
+
+	if c.Quota.MaxCatFactsPerNamespace < 0 {
+		errs = append(errs, errors.New("quota.maxCatFactsPerNamespace must not be negative"))
+	}
+
+	for name := range c.FeatureGates {
+		if !features.DefaultFeatureGate.Known(name) {
+			errs = append(errs, fmt.Errorf("featureGates: unknown feature gate %q", name))
+		}
+	}
+
+	if c.Tracing.Endpoint != "" {
+		if u, err := url.Parse(c.Tracing.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errs = append(errs, fmt.Errorf("tracing.endpoint %q must be an absolute http or https URL", c.Tracing.Endpoint))
+		}
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sampleRatio must be between 0 and 1"))
+	}
+
+	plugin := c.ConsolePlugin
+	if plugin.Replicas < 0 {
+		errs = append(errs, errors.New("consolePlugin.replicas must be positive"))
+	}
+	if plugin.Autoscaling.MinReplicas < 0 || plugin.Autoscaling.MaxReplicas < plugin.Autoscaling.MinReplicas {
+		errs = append(errs, errors.New("consolePlugin.autoscaling replicas must be positive with minReplicas <= maxReplicas"))
+	}
+	if plugin.Autoscaling.TargetCPUUtilization < 0 || plugin.Autoscaling.TargetCPUUtilization > 100 {
+		errs = append(errs, errors.New("consolePlugin.autoscaling.targetCPUUtilization must be between 1 and 100"))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Read, default, and validate a config file. If the file doesn't exist, the
+// default config is returned so an optional ConfigMap can be mounted.
+func LoadOperatorConfig(path string) (*OperatorConfig, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return DefaultOperatorConfig(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseOperatorConfig(data)
+}
+
+// Decode, default, and validate a config file. Unknown fields are rejected.
+func ParseOperatorConfig(data []byte) (*OperatorConfig, error) {
+	c := &OperatorConfig{}
+	if err := yaml.UnmarshalStrict(data, c); err != nil {
+		return nil, fmt.Errorf("unable to parse operator config: %w", err)
+	}
+	c.SetDefaults()
+	if err := c.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid operator config: %w", err)
+	}
+	return c, nil
+}
+
+// Return values from the file for settings that also have a flag, keyed by
+// flag name. Flags that were set on the command line take precedence.
+func (c *OperatorConfig) FlagValues() map[string]string {
+	return map[string]string{
+		"console-plugin-replicas":        strconv.Itoa(int(c.ConsolePlugin.Replicas)),
+		"console-plugin-pdb":             strconv.FormatBool(c.ConsolePlugin.PodDisruptionBudget),
+		"console-plugin-network-policy":  strconv.FormatBool(c.ConsolePlugin.NetworkPolicy),
+		"console-plugin-topology-spread": strconv.FormatBool(c.ConsolePlugin.TopologySpread),
+		"console-plugin-autoscaling":     strconv.FormatBool(c.ConsolePlugin.Autoscaling.Enabled),
+		"max-concurrent-reconciles":      strconv.Itoa(c.Concurrency.MaxConcurrentReconciles),
+		"event-qps":                      strconv.FormatFloat(float64(c.RateLimits.EventQPS), 'f', -1, 32),
+		"event-burst":                    strconv.Itoa(c.RateLimits.EventBurst),
+		"reconcile-base-delay":           c.RateLimits.ReconcileBaseDelay.Duration.String(),
+		"reconcile-max-delay":            c.RateLimits.ReconcileMaxDelay.Duration.String(),
+		"reconcile-qps":                  strconv.FormatFloat(float64(c.RateLimits.ReconcileQPS), 'f', -1, 32),
+		"reconcile-burst":                strconv.Itoa(c.RateLimits.ReconcileBurst),
+		"generation-changed-predicate":   strconv.FormatBool(c.Concurrency.GenerationChangedPredicate),
+		"tracing-endpoint":               c.Tracing.Endpoint,
+		"tracing-sample-ratio":           strconv.FormatFloat(c.Tracing.SampleRatio, 'f', -1, 64),
+	}
+}
+
+// Return the names of settings that differ between two configs and can't be
+// changed without restarting the operator
+func RestartRequired(old, new *OperatorConfig) []string {
+	var changed []string
+	if old.RateLimits != new.RateLimits {
+		changed = append(changed, "rateLimits")
+	}
+	if old.Concurrency != new.Concurrency {
+		changed = append(changed, "concurrency")
+	}
+	if old.ConsolePlugin != new.ConsolePlugin {
+		changed = append(changed, "consolePlugin")
+	}
+	if !maps.Equal(old.FeatureGates, new.FeatureGates) {
+		changed = append(changed, "featureGates")
+	}
+	if old.Tracing != new.Tracing {
+		changed = append(changed, "tracing")
+	}
+	return changed
+}
+
+// Apply the settings that can change while the operator runs: fact sources,
+// icon policy, translation, moderation, and quota
+func (c *OperatorConfig) ApplyRuntimeSettings() {
+	providers := make([]core.FactProvider, 0, len(c.FactSources))
+	for _, source := range c.FactSources {
+		if len(source.Facts) > 0 {
+			providers = append(providers, &core.LibraryProvider{DisplayName: source.Name, Facts: source.Facts})
+			continue
+		}
+		providers = append(providers, &core.CatFactNinjaProvider{URL: source.URL, DisplayName: source.Name, CategoryURL: source.CategoryURL})
+	}
+	core.SetFactProviders(providers...)
+	core.SetAllowedIconNames(c.IconPolicy.AllowedIcons)
+
+	var translators []core.Translator
+	if len(c.Translation.Dictionary) > 0 {
+		translators = append(translators, &core.DictionaryTranslator{Translations: c.Translation.Dictionary})
+	}
+	if c.Translation.URL != "" {
+		translators = append(translators, &core.LibreTranslateTranslator{URL: c.Translation.URL})
+	}
+	core.SetTranslators(translators...)
+
+	var moderators []core.Moderator
+	blocklist := c.Moderation.Blocklist
+	if len(blocklist.Words) > 0 || len(blocklist.Patterns) > 0 {
+		// Validated with the rest of the config
+		if moderator, err := core.NewBlocklistModerator(blocklist.Words, blocklist.Patterns); err == nil {
+			moderators = append(moderators, moderator)
+		}
+	}
+	if c.Moderation.Webhook.URL != "" {
+		moderators = append(moderators, &core.WebhookModerator{URL: c.Moderation.Webhook.URL, IgnoreFailures: c.Moderation.Webhook.IgnoreFailures})
+	}
+	core.SetModerators(moderators...)
+
+	admission.SetDefaultMaxCatFacts(c.Quota.MaxCatFactsPerNamespace)
+}
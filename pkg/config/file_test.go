@@ -0,0 +1,155 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/core"
+)
+
+func TestParseOperatorConfigDefaults(t *testing.T) {
+	c, err := ParseOperatorConfig([]byte(""))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(c, DefaultOperatorConfig()) {
+		t.Errorf("Expected an empty file to produce the default config, got %+v", c)
+	}
+	if c.FactSources[0].URL != core.DefaultFactURL || c.Concurrency.MaxConcurrentReconciles != 1 {
+		t.Errorf("Unexpected defaults %+v", c)
+	}
+}
+
+func TestParseOperatorConfig(t *testing.T) {
+	c, err := ParseOperatorConfig([]byte(`
+apiVersion: config.ryanmillerc.github.io/v1alpha1
+kind: OperatorConfig
+factSources:
+- name: mirror
+  url: https://facts.example.com/fact
+concurrency:
+  maxConcurrentReconciles: 4
+iconPolicy:
+  allowedIcons: [Joy, Hearts]
+consolePlugin:
+  replicas: 3
+  networkPolicy: true
+featureGates:
+  InvalidIconFallback: true
+`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if c.FactSources[0].Name != "mirror" || c.Concurrency.MaxConcurrentReconciles != 4 {
+		t.Errorf("Unexpected config %+v", c)
+	}
+	if c.RateLimits.EventBurst == 0 || c.ConsolePlugin.Autoscaling.MaxReplicas != 4 {
+		t.Errorf("Expected unset fields to be defaulted, got %+v", c)
+	}
+
+	flags := c.FlagValues()
+	if flags["console-plugin-replicas"] != "3" || flags["console-plugin-network-policy"] != "true" || flags["max-concurrent-reconciles"] != "4" {
+		t.Errorf("Unexpected flag values %v", flags)
+	}
+}
+
+func TestParseOperatorConfigInvalid(t *testing.T) {
+	tests := map[string]string{
+		"unknown field": "bogus: true",
+		"wrong kind":    "apiVersion: v1\nkind: ConfigMap",
+		"bad url":       "factSources:\n- name: bad\n  url: not-a-url",
+		"category url":  "factSources:\n- name: bad\n  url: https://a.example.com\n  categoryURL: /facts/{category}",
+		"library url":   "factSources:\n- name: a\n  url: https://a.example.com\n  facts: [Cats are cool!]",
+		"duplicate":     "factSources:\n- name: a\n  url: https://a.example.com\n- name: a\n  url: https://b.example.com",
+		"invalid icon":  "iconPolicy:\n  allowedIcons: [Dog]",
+		"feature gate":  "featureGates:\n  Unknown: true",
+		"autoscaling":   "consolePlugin:\n  autoscaling:\n    minReplicas: 5\n    maxReplicas: 2",
+		"tracing url":   "tracing:\n  endpoint: otel-collector:4318",
+		"sample ratio":  "tracing:\n  sampleRatio: 2",
+		"locale":        "translation:\n  dictionary:\n    not a locale: {}",
+		"translate url": "translation:\n  url: libretranslate:5000",
+		"blocklist":     "moderation:\n  blocklist:\n    patterns: ['(unclosed']",
+		"webhook url":   "moderation:\n  webhook:\n    url: /moderate",
+		"quota":         "quota:\n  maxCatFactsPerNamespace: -1",
+	}
+	for name, data := range tests {
+		if _, err := ParseOperatorConfig([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestLoadOperatorConfigMissing(t *testing.T) {
+	c, err := LoadOperatorConfig(filepath.Join(t.TempDir(), "missing.yaml"))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(c, DefaultOperatorConfig()) {
+		t.Errorf("Expected the default config for a missing file, got %+v", c)
+	}
+}
+
+func TestRestartRequired(t *testing.T) {
+	old := DefaultOperatorConfig()
+	next := DefaultOperatorConfig()
+	next.IconPolicy.AllowedIcons = []string{"Joy"}
+	next.FactSources = []FactSource{{Name: "mirror", URL: "https://facts.example.com"}}
+	if changed := RestartRequired(old, next); len(changed) != 0 {
+		t.Errorf("Expected fact sources and icon policy to reload, got %v", changed)
+	}
+
+	next.Concurrency.MaxConcurrentReconciles = 2
+	next.FeatureGates = map[string]bool{"Example": true}
+	if changed := RestartRequired(old, next); !reflect.DeepEqual(changed, []string{"concurrency", "featureGates"}) {
+		t.Errorf("Unexpected settings requiring restart %v", changed)
+	}
+}
+
+func TestConfigReloader(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	var reloaded *OperatorConfig
+	reloader := &ConfigReloader{
+		Path:     path,
+		Current:  DefaultOperatorConfig(),
+		OnChange: func(_, next *OperatorConfig) { reloaded = next },
+	}
+
+	write := func(data string) {
+		if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write("iconPolicy:\n  allowedIcons: [Joy]\n")
+	reloader.reload()
+	if reloaded == nil || !reflect.DeepEqual(reloaded.IconPolicy.AllowedIcons, []string{"Joy"}) {
+		t.Fatalf("Expected config to be reloaded, got %+v", reloaded)
+	}
+
+	// Invalid files are ignored
+	reloaded = nil
+	write("iconPolicy:\n  allowedIcons: [Dog]\n")
+	reloader.reload()
+	if reloaded != nil || !strings.Contains(strings.Join(reloader.Current.IconPolicy.AllowedIcons, ","), "Joy") {
+		t.Errorf("Expected invalid config to be ignored")
+	}
+
+	// Unchanged files aren't reloaded
+	reloader.reload()
+	if reloaded != nil {
+		t.Errorf("Expected unchanged config not to be reloaded")
+	}
+}
+
+func TestSampleOperatorConfig(t *testing.T) {
+	c, err := LoadOperatorConfig(filepath.Join("..", "..", "config", "manager", "controller_manager_config.yaml"))
+	if err != nil {
+		t.Fatalf("Sample config is invalid: %v", err)
+	}
+	if !reflect.DeepEqual(c.FlagValues(), DefaultOperatorConfig().FlagValues()) {
+		t.Errorf("Expected sample config to match the defaults, got %+v", c)
+	}
+}
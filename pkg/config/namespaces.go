@@ -0,0 +1,38 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// Environment variable with the namespaces the operator watches for CatFacts.
+// OLM sets it from the olm.targetNamespaces annotation.
+const WatchNamespaceEnvVar = "WATCH_NAMESPACE"
+
+// Return the namespaces to watch for CatFacts from WATCH_NAMESPACE.
+//
+// WATCH_NAMESPACE can be a single namespace or a comma-separated list. If it's
+// unset or empty, nil is returned and all namespaces are watched.
+func WatchNamespaces() ([]string, error) {
+	return parseNamespaces(os.Getenv(WatchNamespaceEnvVar))
+}
+
+func parseNamespaces(value string) ([]string, error) {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, ns := range strings.Split(value, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || seen[ns] {
+			continue
+		}
+		if errs := validation.IsDNS1123Label(ns); len(errs) > 0 {
+			return nil, fmt.Errorf("invalid namespace %q in %s: %s", ns, WatchNamespaceEnvVar, strings.Join(errs, ", "))
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces, nil
+}
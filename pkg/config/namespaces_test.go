@@ -0,0 +1,32 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseNamespaces(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{" , ", nil},
+		{"cats", []string{"cats"}},
+		{"cats,kittens", []string{"cats", "kittens"}},
+		{" cats , kittens,cats,", []string{"cats", "kittens"}},
+	}
+	for _, test := range tests {
+		got, err := parseNamespaces(test.value)
+		if err != nil {
+			t.Errorf("parseNamespaces(%q) returned error: %v", test.value, err)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("parseNamespaces(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+
+	if _, err := parseNamespaces("cats,Not_A_Namespace"); err == nil {
+		t.Errorf("Expected an error for an invalid namespace")
+	}
+}
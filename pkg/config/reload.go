@@ -0,0 +1,97 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"time"
+
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var configLog = ctrl.Log.WithName("config")
+
+// How often the config file is checked for changes. ConfigMap volume updates
+// take up to a minute to reach the pod, so this doesn't need to be fast.
+const reloadInterval = 10 * time.Second
+
+// ConfigReloader watches the config file and calls OnChange with the new
+// config when it changes. It implements manager.Runnable.
+//
+// The file is polled rather than watched with inotify because kubelet
+// updates ConfigMap volumes by swapping a symlink. Invalid files are logged
+// and ignored, so a bad edit doesn't take down a running operator.
+type ConfigReloader struct {
+	// Path of the config file
+	Path string
+
+	// Config currently in use
+	Current *OperatorConfig
+
+	// Called with the previous and new config after the file changes
+	OnChange func(old, new *OperatorConfig)
+
+	data []byte
+}
+
+// Start polls the config file until ctx is cancelled.
+func (r *ConfigReloader) Start(ctx context.Context) error {
+	r.data, _ = readConfigFile(r.Path)
+	ticker := time.NewTicker(reloadInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			r.reload()
+		}
+	}
+}
+
+// NeedLeaderElection returns false so every replica reloads its config.
+func (r *ConfigReloader) NeedLeaderElection() bool {
+	return false
+}
+
+// Reload the config file if its contents changed
+func (r *ConfigReloader) reload() {
+	data, err := readConfigFile(r.Path)
+	if err != nil {
+		configLog.Error(err, "unable to read config file", "path", r.Path)
+		return
+	}
+	if bytes.Equal(data, r.data) {
+		return
+	}
+	r.data = data
+
+	next := DefaultOperatorConfig()
+	if len(data) > 0 {
+		next, err = ParseOperatorConfig(data)
+		if err != nil {
+			configLog.Error(err, "ignoring invalid config file", "path", r.Path)
+			return
+		}
+	}
+
+	configLog.Info("Config file changed, reloading", "path", r.Path)
+	if changed := RestartRequired(r.Current, next); len(changed) > 0 {
+		configLog.Info("Some settings only take effect after a restart", "settings", changed)
+	}
+	previous := r.Current
+	r.Current = next
+	if r.OnChange != nil {
+		r.OnChange(previous, next)
+	}
+}
+
+// Read the config file. A missing file is read as empty.
+func readConfigFile(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	return data, err
+}
@@ -0,0 +1,141 @@
+/*
+Serving certificate handling for the OpenShift Dynamic Console plugin.
+
+OpenShift's service CA generates the plugin's serving certificate into the
+Secret named by the service.beta.openshift.io/serving-cert-secret-name
+annotation on the plugin Service. nginx only reads the certificate at startup,
+so when the service CA rotates it the plugin pods have to be restarted. A hash
+of the certificate is stamped on the Deployment's pod template so that every
+rotation triggers a rolling restart.
+
+The plugin has no resource of its own with a status, and the Deployment's
+status belongs to the deployment controller, so the certificate expiry is
+reported as a Deployment annotation and the
+catfacts_console_plugin_cert_expiry_timestamp_seconds metric instead.
+*/
+
+package console
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+)
+
+const (
+	// Pod template annotation with a hash of the serving certificate. Changing
+	// it rolls the plugin Deployment.
+	ServingCertHashAnnotation = "ryanmillerc.github.io/serving-cert-hash"
+
+	// Deployment annotation with the expiry (RFC 3339) of the serving
+	// certificate currently mounted by the plugin.
+	ServingCertExpiryAnnotation = "ryanmillerc.github.io/serving-cert-expiry"
+)
+
+// Return the name of the Secret holding the plugin serving certificate
+func ServingCertSecretName() string {
+	return fmt.Sprintf("%s-cert", getPluginName())
+}
+
+// Update the plugin Deployment to match the current serving certificate.
+//
+// If the certificate changed since the Deployment was last updated, the pod
+// template annotation changes and the Deployment rolls. Nothing is done if the
+// Secret or Deployment don't exist yet, or if the ServingCertRotation feature
+// is disabled.
+func SyncServingCert(ctx context.Context, kclient client.Client) error {
+	if !features.Enabled(features.ServingCertRotation) {
+		return nil
+	}
+	namespace, err := ControllerNamespace()
+	if err != nil {
+		return err
+	}
+
+	var deployment appsv1.Deployment
+	key := client.ObjectKey{Namespace: namespace, Name: getPluginName()}
+	if err := kclient.Get(ctx, key, &deployment); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	patch := client.MergeFrom(deployment.DeepCopy())
+	changed, err := applyServingCert(ctx, kclient, &deployment)
+	if err != nil || !changed {
+		return err
+	}
+
+	consoleLog.Info(
+		"Serving certificate changed, rolling console dynamic plugin",
+		"expiry",
+		deployment.Annotations[ServingCertExpiryAnnotation],
+	)
+	return kclient.Patch(ctx, &deployment, patch)
+}
+
+// Stamp serving certificate hash and expiry annotations on a Deployment, and
+// export the expiry as a metric. Returns true if the Deployment was changed.
+func applyServingCert(ctx context.Context, kclient client.Client, deployment *appsv1.Deployment) (bool, error) {
+	var secret corev1.Secret
+	key := client.ObjectKey{Namespace: deployment.Namespace, Name: ServingCertSecretName()}
+	if err := kclient.Get(ctx, key, &secret); err != nil {
+		return false, client.IgnoreNotFound(err)
+	}
+
+	hash := hashServingCert(&secret)
+	expiry := ""
+	notAfter, err := getCertExpiry(secret.Data[corev1.TLSCertKey])
+	if err != nil {
+		consoleLog.Error(err, "unable to read serving certificate expiry")
+	} else {
+		expiry = notAfter.UTC().Format(time.RFC3339)
+		metrics.ConsolePluginCertExpiry.Set(float64(notAfter.Unix()))
+	}
+
+	if deployment.Spec.Template.Annotations[ServingCertHashAnnotation] == hash &&
+		deployment.Annotations[ServingCertExpiryAnnotation] == expiry {
+		return false, nil
+	}
+
+	if deployment.Spec.Template.Annotations == nil {
+		deployment.Spec.Template.Annotations = map[string]string{}
+	}
+	deployment.Spec.Template.Annotations[ServingCertHashAnnotation] = hash
+	if deployment.Annotations == nil {
+		deployment.Annotations = map[string]string{}
+	}
+	deployment.Annotations[ServingCertExpiryAnnotation] = expiry
+	return true, nil
+}
+
+// Return a hash of the certificate and key in a TLS Secret
+func hashServingCert(secret *corev1.Secret) string {
+	h := sha256.New()
+	h.Write(secret.Data[corev1.TLSCertKey])
+	h.Write(secret.Data[corev1.TLSPrivateKeyKey])
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Return the NotAfter time of the first certificate in a PEM bundle
+func getCertExpiry(data []byte) (time.Time, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return time.Time{}, errors.New("no PEM certificate found")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return cert.NotAfter, nil
+}
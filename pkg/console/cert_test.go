@@ -0,0 +1,106 @@
+package console
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+)
+
+// Return a self-signed PEM certificate that expires at notAfter
+func newTestCert(t *testing.T, notAfter time.Time) []byte {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Unable to generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "cat-facts-operator-console-plugin"},
+		NotBefore:    notAfter.Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("Unable to create certificate: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestSyncServingCert(t *testing.T) {
+	t.Setenv("CONTROLLER_NAMESPACE", "cat-facts-operator")
+	ctx := context.TODO()
+
+	notAfter := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: ServingCertSecretName(), Namespace: "cat-facts-operator"},
+		Data: map[string][]byte{
+			corev1.TLSCertKey:       newTestCert(t, notAfter),
+			corev1.TLSPrivateKeyKey: []byte("key"),
+		},
+	}
+	deployment := getDeployment(getPluginName(), "cat-facts-operator", DefaultPluginOptions())
+	kclient := newFakeClient(secret, &deployment)
+
+	if err := SyncServingCert(ctx, kclient); err != nil {
+		t.Fatalf("SyncServingCert returned error: %v", err)
+	}
+	var found appsv1.Deployment
+	if err := kclient.Get(ctx, client.ObjectKeyFromObject(&deployment), &found); err != nil {
+		t.Fatalf("Unable to get Deployment: %v", err)
+	}
+	firstHash := found.Spec.Template.Annotations[ServingCertHashAnnotation]
+	if firstHash == "" {
+		t.Fatalf("Expected %s to be set on the pod template", ServingCertHashAnnotation)
+	}
+	if got := found.Annotations[ServingCertExpiryAnnotation]; got != "2030-01-02T03:04:05Z" {
+		t.Errorf("Expected expiry 2030-01-02T03:04:05Z, got %q", got)
+	}
+	if got := testutil.ToFloat64(metrics.ConsolePluginCertExpiry); got != float64(notAfter.Unix()) {
+		t.Errorf("Expected the expiry metric to be %d, got %v", notAfter.Unix(), got)
+	}
+
+	// Rotate the certificate
+	secret.Data[corev1.TLSCertKey] = newTestCert(t, notAfter.AddDate(1, 0, 0))
+	if err := kclient.Update(ctx, secret); err != nil {
+		t.Fatalf("Unable to update Secret: %v", err)
+	}
+	if err := SyncServingCert(ctx, kclient); err != nil {
+		t.Fatalf("SyncServingCert returned error: %v", err)
+	}
+	if err := kclient.Get(ctx, client.ObjectKeyFromObject(&deployment), &found); err != nil {
+		t.Fatalf("Unable to get Deployment: %v", err)
+	}
+	if found.Spec.Template.Annotations[ServingCertHashAnnotation] == firstHash {
+		t.Errorf("Expected %s to change after rotation", ServingCertHashAnnotation)
+	}
+	if got := found.Annotations[ServingCertExpiryAnnotation]; got != "2031-01-02T03:04:05Z" {
+		t.Errorf("Expected expiry 2031-01-02T03:04:05Z, got %q", got)
+	}
+	if got := testutil.ToFloat64(metrics.ConsolePluginCertExpiry); got != float64(notAfter.AddDate(1, 0, 0).Unix()) {
+		t.Errorf("Expected the expiry metric to follow the rotated certificate, got %v", got)
+	}
+}
+
+func TestSyncServingCertMissingSecret(t *testing.T) {
+	t.Setenv("CONTROLLER_NAMESPACE", "cat-facts-operator")
+	deployment := getDeployment(getPluginName(), "cat-facts-operator", DefaultPluginOptions())
+	kclient := newFakeClient(&deployment)
+	if err := SyncServingCert(context.TODO(), kclient); err != nil {
+		t.Fatalf("SyncServingCert returned error: %v", err)
+	}
+}
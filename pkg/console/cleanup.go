@@ -0,0 +1,146 @@
+/*
+Code to remove the OpenShift Dynamic Console plugin when the operator is
+uninstalled.
+
+The ConsolePlugin is cluster-scoped, so it can't be owned by anything in the
+operator namespace and would be left behind after an uninstall. Instead, the
+operator's own Deployment is used as an anchor. A finalizer is added to it and,
+once the Deployment is deleted, the plugin resources are removed before the
+finalizer is released.
+*/
+
+package console
+
+import (
+	"context"
+	"os"
+
+	consolev1 "github.com/openshift/api/console/v1"
+	operatorv1 "github.com/openshift/api/operator/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
+)
+
+// CleanupFinalizer is added to the anchor Deployment so console plugin
+// resources can be removed before the operator goes away.
+const CleanupFinalizer = "ryanmillerc.github.io/console-plugin-cleanup"
+
+// Return the key of the anchor object, the operator's own Deployment.
+//
+// The name defaults to config.OperatorDeploymentName. Set
+// OPERATOR_DEPLOYMENT_NAME if the Deployment was renamed.
+func AnchorKey() (client.ObjectKey, error) {
+	namespace, err := ControllerNamespace()
+	if err != nil {
+		return client.ObjectKey{}, err
+	}
+	name := config.OperatorDeploymentName
+	if n, ok := os.LookupEnv("OPERATOR_DEPLOYMENT_NAME"); ok && n != "" {
+		name = n
+	}
+	return client.ObjectKey{Namespace: namespace, Name: name}, nil
+}
+
+// Remove all console plugin resources.
+//
+// The plugin is disabled in the cluster Console operator config, then the
+// ConsolePlugin, its Deployment, Service, and any hardening resources are
+// deleted. Resources that don't exist, or APIs that aren't served, are
+// skipped.
+func Cleanup(ctx context.Context, kclient client.Client) error {
+	name := getPluginName()
+	namespace, err := ControllerNamespace()
+	if err != nil {
+		return err
+	}
+
+	if err := disableConsolePlugin(ctx, kclient, name); err != nil {
+		return err
+	}
+
+	consoleLog.Info("Deleting console dynamic plugin resources")
+	objects := []client.Object{
+		&consolev1.ConsolePlugin{ObjectMeta: metav1.ObjectMeta{Name: name}},
+		&corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+		&appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+		&policyv1.PodDisruptionBudget{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+		&networkingv1.NetworkPolicy{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+		&autoscalingv2.HorizontalPodAutoscaler{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}},
+	}
+	for _, obj := range objects {
+		err := kclient.Delete(ctx, obj, client.PropagationPolicy(metav1.DeletePropagationBackground))
+		if err != nil && !kerrors.IsNotFound(err) && !meta.IsNoMatchError(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Run a one-shot cleanup outside of the manager.
+//
+// This removes the console plugin resources and releases the finalizer on the
+// anchor Deployment, so scripted teardown doesn't get stuck waiting on an
+// operator that is no longer running.
+func RunCleanup(ctx context.Context) error {
+	kclient, err := newClient()
+	if err != nil {
+		return err
+	}
+
+	if err := Cleanup(ctx, kclient); err != nil {
+		return err
+	}
+
+	key, err := AnchorKey()
+	if err != nil {
+		return err
+	}
+	var anchor appsv1.Deployment
+	if err := kclient.Get(ctx, key, &anchor); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	if controllerutil.RemoveFinalizer(&anchor, CleanupFinalizer) {
+		consoleLog.Info("Removing cleanup finalizer", "deployment", key.String())
+		return kclient.Update(ctx, &anchor)
+	}
+	return nil
+}
+
+// Remove the plugin from the list of enabled plugins in the cluster Console
+// operator config
+func disableConsolePlugin(ctx context.Context, kclient client.Client, name string) error {
+	var consoleConfig operatorv1.Console
+	err := kclient.Get(ctx, client.ObjectKey{Name: "cluster"}, &consoleConfig)
+	if err != nil {
+		if kerrors.IsNotFound(err) || meta.IsNoMatchError(err) {
+			return nil
+		}
+		return err
+	}
+
+	plugins := []string{}
+	for _, plugin := range consoleConfig.Spec.Plugins {
+		if plugin != name {
+			plugins = append(plugins, plugin)
+		}
+	}
+	if len(plugins) == len(consoleConfig.Spec.Plugins) {
+		return nil // Plugin isn't enabled
+	}
+
+	consoleLog.Info("Disabling console dynamic plugin")
+	patch := client.MergeFrom(consoleConfig.DeepCopy())
+	consoleConfig.Spec.Plugins = plugins
+	return kclient.Patch(ctx, &consoleConfig, patch)
+}
@@ -0,0 +1,58 @@
+package console
+
+import (
+	"context"
+	"testing"
+
+	operatorv1 "github.com/openshift/api/operator/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newFakeClient(objects ...client.Object) client.Client {
+	return fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(objects...).Build()
+}
+
+func TestCleanup(t *testing.T) {
+	t.Setenv("CONTROLLER_NAMESPACE", "cat-facts-operator")
+	name := getPluginName()
+
+	deployment := getDeployment(name, "cat-facts-operator", DefaultPluginOptions())
+	service := getService(name, "cat-facts-operator")
+	consolePlugin := getConsolePlugin(name, "cat-facts-operator")
+	consoleConfig := &operatorv1.Console{
+		ObjectMeta: metav1.ObjectMeta{Name: "cluster"},
+		Spec: operatorv1.ConsoleSpec{
+			Plugins: []string{"other-plugin", name},
+		},
+	}
+	kclient := newFakeClient(&deployment, &service, &consolePlugin, consoleConfig)
+
+	if err := Cleanup(context.TODO(), kclient); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+
+	for _, obj := range []client.Object{&deployment, &service, &consolePlugin} {
+		err := kclient.Get(context.TODO(), client.ObjectKeyFromObject(obj), obj)
+		if !kerrors.IsNotFound(err) {
+			t.Errorf("Expected %s to be deleted, got error %v", obj.GetName(), err)
+		}
+	}
+
+	var found operatorv1.Console
+	if err := kclient.Get(context.TODO(), client.ObjectKey{Name: "cluster"}, &found); err != nil {
+		t.Fatalf("Unable to get Console config: %v", err)
+	}
+	if len(found.Spec.Plugins) != 1 || found.Spec.Plugins[0] != "other-plugin" {
+		t.Errorf("Expected only other-plugin to be enabled, got %v", found.Spec.Plugins)
+	}
+}
+
+func TestCleanupNothingToDo(t *testing.T) {
+	t.Setenv("CONTROLLER_NAMESPACE", "cat-facts-operator")
+	if err := Cleanup(context.TODO(), newFakeClient()); err != nil {
+		t.Fatalf("Cleanup returned error: %v", err)
+	}
+}
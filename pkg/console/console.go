@@ -13,128 +13,151 @@ import (
 	"os"
 	"strings"
 
-	configv1 "github.com/openshift/api/config/v1"
 	consolev1 "github.com/openshift/api/console/v1"
-	"golang.org/x/mod/semver"
+	operatorv1 "github.com/openshift/api/operator/v1"
 	appsv1 "k8s.io/api/apps/v1"
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
 	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	policyv1 "k8s.io/api/policy/v1"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/util/intstr"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
+	"github.com/ryanmillerc/cat-facts-operator/pkg/cluster"
 	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
 )
 
 var consoleLog = ctrl.Log.WithName("console")
 
 // +kubebuilder:rbac:namespace=cat-facts-operator,groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:namespace=cat-facts-operator,groups=core,resources=services,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:namespace=cat-facts-operator,groups=core,resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:namespace=cat-facts-operator,groups=policy,resources=poddisruptionbudgets,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:namespace=cat-facts-operator,groups=networking.k8s.io,resources=networkpolicies,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:namespace=cat-facts-operator,groups=autoscaling,resources=horizontalpodautoscalers,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=console.openshift.io,resources=consoleplugins,verbs=get;list;watch;create;update;patch;delete
-// +kubebuilder:rbac:groups=config.openshift.io,resources=clusterversions,verbs=get
+// +kubebuilder:rbac:groups=operator.openshift.io,resources=consoles,verbs=get;update;patch
 
 // Deploy OpenShift console dynamic plugin.
 //
 // Console plugins require 3 resources: a Deployment, a Service, and a
 // ConsolePlugin. If any of the listed resources exist from a previous
-// installation, they will be untouched.
+// installation, they will be updated.
 //
-// If the cluster does not meet the minimum version set by
-// config.MinConsolePluginOCPVer, console plugin resources will not be deployed.
+// Hardening resources (PodDisruptionBudget, NetworkPolicy, and
+// HorizontalPodAutoscaler) are managed according to options.
 //
-// If the cluster is not an OpenShift cluster, this function will error.
-func DeployConsolePlugin() error {
-	// We can't use the controller client because it hasn't been registered with
-	// the manager yet. There's no good way register it without entering the
-	// reconsile loop. So it's easiest to make our own client and pass it around
-	// to functions in this package.
-	scheme := runtime.NewScheme()
-	appsv1.AddToScheme(scheme)    // Deployment
-	corev1.AddToScheme(scheme)    // Service
-	consolev1.AddToScheme(scheme) // ConsolePlugin
-	configv1.AddToScheme(scheme)  // ClusterVersion
-	kubeconfig := ctrl.GetConfigOrDie()
-	kclient, err := client.New(kubeconfig, client.Options{Scheme: scheme})
-	if err != nil {
-		return err
-	}
-
-	// Validate OpenShift version meets minimum requirements. If the version
-	// requirement is not met, do not install the console plugin.
-	ocpVersion, err := getOpenShiftVersion(kclient)
-	if err != nil {
-		return errors.New("unable to validate OpenShift version")
-	}
-	shouldInstallPlugin := isOpenShiftVersionOk(ocpVersion)
-	if shouldInstallPlugin {
-		consoleLog.Info(
-			"OpenShift version supports console dynamic plugins",
-			"openShiftVersion",
-			ocpVersion,
-			"minimumRequiredVersion",
-			config.MinConsolePluginOCPVer,
-		)
-	} else {
+// If the cluster capabilities show the cluster can't run console plugins
+// (not OpenShift, no ConsolePlugin API, or older than
+// config.MinConsolePluginOCPVer), console plugin resources will not be
+// deployed and no error is returned.
+func DeployConsolePlugin(capabilities cluster.ClusterCapabilities, options PluginOptions) error {
+	if !capabilities.SupportsConsolePlugin() {
 		consoleLog.Info(
-			"OpenShift version does not support console dynamic plugins",
+			"Cluster does not support console dynamic plugins, skipping console plugin",
+			"consolePluginAPI",
+			capabilities.ConsolePlugin,
 			"openShiftVersion",
-			ocpVersion,
+			capabilities.OpenShiftVersion,
 			"minimumRequiredVersion",
 			config.MinConsolePluginOCPVer,
 		)
-		return nil // Do not continue since OCP version doesn't support plugins
+		return nil
 	}
+	consoleLog.Info(
+		"Cluster supports console dynamic plugins",
+		"openShiftVersion",
+		capabilities.OpenShiftVersion,
+		"minimumRequiredVersion",
+		config.MinConsolePluginOCPVer,
+	)
 
-	// All resources (Deployment, Service, and ConsolePlugin) share the same
-	// name and namespace
-	name := fmt.Sprintf("%s-console-plugin", config.OperatorName)
-	namespace, err := getControllerNamespace()
+	kclient, err := newClient()
 	if err != nil {
 		return err
 	}
 
-	deployment := getDeployment(name, namespace)
-	createOrUpdateDeployment(kclient, &deployment)
+	// All resources (Deployment, Service, and ConsolePlugin) share the same
+	// name and namespace
+	name := getPluginName()
+	namespace, err := ControllerNamespace()
 	if err != nil {
 		return err
 	}
 
-	service := getService(name, namespace)
-	createOrUpdateService(kclient, &service)
-	if err != nil {
-		return err
+	// Optional resources are removed when they are disabled, so turning an
+	// option off cleans up what an earlier run created.
+	deployment := getDeployment(name, namespace, options)
+	// Keep the current serving certificate hash so restarting the operator
+	// doesn't roll the plugin
+	if features.Enabled(features.ServingCertRotation) {
+		if _, err := applyServingCert(context.TODO(), kclient, &deployment); err != nil {
+			return err
+		}
 	}
-
+	service := getService(name, namespace)
 	consolePlugin := getConsolePlugin(name, namespace)
-	createOrUpdateConsolePlugin(kclient, &consolePlugin)
-	if err != nil {
-		return err
+	podDisruptionBudget := getPodDisruptionBudget(name, namespace)
+	networkPolicy := getNetworkPolicy(name, namespace)
+	horizontalPodAutoscaler := getHorizontalPodAutoscaler(name, namespace, options.Autoscaling)
+	objects := []struct {
+		obj     client.Object
+		enabled bool
+	}{
+		{&deployment, true},
+		{&service, true},
+		{&consolePlugin, true},
+		{&podDisruptionBudget, options.PodDisruptionBudget},
+		{&networkPolicy, options.NetworkPolicy},
+		{&horizontalPodAutoscaler, options.Autoscaling.Enabled},
+	}
+	for _, o := range objects {
+		if o.enabled {
+			err = createOrUpdate(kclient, o.obj)
+		} else {
+			err = deleteIfExists(kclient, o.obj)
+		}
+		if err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Return semantic version of the running OpenShift cluster
-func getOpenShiftVersion(kclient client.Client) (string, error) {
-	var clusterVersion configv1.ClusterVersion
-	err := kclient.Get(context.TODO(), client.ObjectKey{Name: "version"}, &clusterVersion, &client.GetOptions{})
-	if err != nil {
-		return "", err
-	}
-	// Desired version appears to be the current OpenShift cluster version
-	return clusterVersion.Status.Desired.Version, nil
+// Return a client that knows about every type this package works with.
+//
+// We can't use the controller client because it hasn't been registered with
+// the manager yet. There's no good way register it without entering the
+// reconsile loop. So it's easiest to make our own client and pass it around
+// to functions in this package.
+func newClient() (client.Client, error) {
+	kubeconfig := ctrl.GetConfigOrDie()
+	return client.New(kubeconfig, client.Options{Scheme: newScheme()})
+}
+
+// Return a scheme with every type this package works with
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	appsv1.AddToScheme(scheme)        // Deployment
+	corev1.AddToScheme(scheme)        // Service
+	policyv1.AddToScheme(scheme)      // PodDisruptionBudget
+	networkingv1.AddToScheme(scheme)  // NetworkPolicy
+	autoscalingv2.AddToScheme(scheme) // HorizontalPodAutoscaler
+	consolev1.AddToScheme(scheme)     // ConsolePlugin
+	operatorv1.AddToScheme(scheme)    // Console operator config
+	return scheme
 }
 
-// Returns true if the passed semantic version is equal to or greater than the
-// minimconfig.OperatorName),um required version.
-func isOpenShiftVersionOk(ocpVersion string) bool {
-	// semver.Compare will return:
-	// 0 if the versions match
-	// 1 if ocpVerion is greater than minVersion
-	// -1 if ocpVerion is less than minVersion
-	return semver.Compare(ocpVersion, config.MinConsolePluginOCPVer) >= 0
+// Return the name shared by all console plugin resources
+func getPluginName() string {
+	return fmt.Sprintf("%s-console-plugin", config.OperatorName)
 }
 
 // Return the namespace of the running controller
@@ -146,7 +169,7 @@ func isOpenShiftVersionOk(ocpVersion string) bool {
 // errors, when running outside of Kubernetes, set CONTROLLER_NAMESPACE
 // environment variable to whatever namespace you would deploy the controller
 // into if deploying on a cluster.
-func getControllerNamespace() (string, error) {
+func ControllerNamespace() (string, error) {
 	// This way assumes you've set the POD_NAMESPACE environment variable using the downward API.
 	// This check has to be done first for backwards compatibility with the way InClusterConfig was originally set up
 	if ns, ok := os.LookupEnv("CONTROLLER_NAMESPACE"); ok {
@@ -163,39 +186,53 @@ func getControllerNamespace() (string, error) {
 	return "", errors.New("could not determine controller namespace. Set CONTROLLER_NAMESPACE environment variable if running controller outside of cluster")
 }
 
-// Create or update the Deployment for a console dynamic plugin
-func createOrUpdateDeployment(kclient client.Client, deployment *appsv1.Deployment) error {
-	var found appsv1.Deployment
-	key := client.ObjectKeyFromObject(deployment)
-	err := kclient.Get(context.TODO(), key, &found, &client.GetOptions{})
-	create := false
+// Create or update a resource for a console dynamic plugin
+func createOrUpdate(kclient client.Client, obj client.Object) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	found := obj.DeepCopyObject().(client.Object)
+	err := kclient.Get(context.TODO(), client.ObjectKeyFromObject(obj), found, &client.GetOptions{})
 	if err != nil {
-		if kerrors.IsNotFound(err) {
-			create = true
-		} else {
+		if !kerrors.IsNotFound(err) {
 			return err
 		}
+		consoleLog.Info(fmt.Sprintf("Creating %s for console dynamic plugin", kind))
+		return kclient.Create(context.TODO(), obj, &client.CreateOptions{})
 	}
 
-	if create {
-		consoleLog.Info("Creating Deployment for console dynamic plugin")
-		err := kclient.Create(context.TODO(), deployment, &client.CreateOptions{})
-		if err != nil {
-			return err
-		}
-	} else {
-		consoleLog.Info("Updating Deployment for console dynamic plugin")
-		deployment.DeepCopyInto(&found)
-		err := kclient.Update(context.TODO(), &found, &client.UpdateOptions{})
-		if err != nil {
-			return err
-		}
+	consoleLog.Info(fmt.Sprintf("Updating %s for console dynamic plugin", kind))
+	obj.SetResourceVersion(found.GetResourceVersion())
+	// A Deployment without replicas is scaled by its HPA. Keep the HPA's
+	// replicas instead of letting the API server default them to 1.
+	if deployment, ok := obj.(*appsv1.Deployment); ok && deployment.Spec.Replicas == nil {
+		deployment.Spec.Replicas = found.(*appsv1.Deployment).Spec.Replicas
 	}
+	return kclient.Update(context.TODO(), obj, &client.UpdateOptions{})
+}
 
+// Delete a resource for a console dynamic plugin if it exists
+func deleteIfExists(kclient client.Client, obj client.Object) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	err := kclient.Delete(context.TODO(), obj, &client.DeleteOptions{})
+	if err != nil {
+		return client.IgnoreNotFound(err)
+	}
+	consoleLog.Info(fmt.Sprintf("Deleted %s for console dynamic plugin", kind))
 	return nil
 }
 
-func getDeployment(name string, namespace string) appsv1.Deployment {
+func getDeployment(name string, namespace string, options PluginOptions) appsv1.Deployment {
+	// Leave replicas unset when autoscaling. createOrUpdate keeps the replicas
+	// the HPA set.
+	var replicas *int32
+	if !options.Autoscaling.Enabled {
+		replicas = int32Ptr(options.Replicas)
+	}
+
+	var topologySpreadConstraints []corev1.TopologySpreadConstraint
+	if options.TopologySpread {
+		topologySpreadConstraints = getTopologySpreadConstraints(name)
+	}
+
 	deployment := appsv1.Deployment{
 		TypeMeta: metav1.TypeMeta{
 			Kind:       "Deployment",
@@ -209,7 +246,7 @@ func getDeployment(name string, namespace string) appsv1.Deployment {
 			},
 		},
 		Spec: appsv1.DeploymentSpec{
-			Replicas: int32Ptr(1),
+			Replicas: replicas,
 			Selector: &metav1.LabelSelector{
 				MatchLabels: map[string]string{
 					"app": name,
@@ -236,21 +273,20 @@ func getDeployment(name string, namespace string) appsv1.Deployment {
 					Containers: []corev1.Container{
 						{
 							Name:  name,
-							Image: fmt.Sprintf("%s:%s", config.ConsolePluginImage, config.Version),
+							Image: options.Image,
 							Ports: []corev1.ContainerPort{
 								{
 									ContainerPort: 9443,
 									Protocol:      "TCP",
 								},
 							},
-							// TODO: Get this working
-							// Resources: corev1.ResourceRequirements{
-							// 	Requests: map[corev1.ResourceName]resource.Quantity{
-							// 		"": {
-							// 			Format: "",
-							// 		},
-							// 	},
-							// },
+							// Requests are required for the HPA to compute CPU utilization
+							Resources: corev1.ResourceRequirements{
+								Requests: corev1.ResourceList{
+									corev1.ResourceCPU:    resource.MustParse("10m"),
+									corev1.ResourceMemory: resource.MustParse("50Mi"),
+								},
+							},
 							VolumeMounts: []corev1.VolumeMount{
 								{
 									Name:      fmt.Sprintf("%s-cert", name),
@@ -258,7 +294,7 @@ func getDeployment(name string, namespace string) appsv1.Deployment {
 									MountPath: "/var/cert",
 								},
 							},
-							ImagePullPolicy: "Always",
+							ImagePullPolicy: getImagePullPolicy(options.Image),
 							SecurityContext: &corev1.SecurityContext{
 								Capabilities: &corev1.Capabilities{
 									Drop: []corev1.Capability{"ALL"},
@@ -267,8 +303,9 @@ func getDeployment(name string, namespace string) appsv1.Deployment {
 							},
 						},
 					},
-					RestartPolicy: "Always",
-					DNSPolicy:     "ClusterFirst",
+					TopologySpreadConstraints: topologySpreadConstraints,
+					RestartPolicy:             "Always",
+					DNSPolicy:                 "ClusterFirst",
 					SecurityContext: &corev1.PodSecurityContext{
 						RunAsNonRoot: boolPtr(true),
 						SeccompProfile: &corev1.SeccompProfile{
@@ -284,38 +321,6 @@ func getDeployment(name string, namespace string) appsv1.Deployment {
 	return deployment
 }
 
-// Create or update the Service for a console dynamic plugin
-func createOrUpdateService(kclient client.Client, service *corev1.Service) error {
-	var found corev1.Service
-	key := client.ObjectKeyFromObject(service)
-	err := kclient.Get(context.TODO(), key, &found, &client.GetOptions{})
-	create := false
-	if err != nil {
-		if kerrors.IsNotFound(err) {
-			create = true
-		} else {
-			return err
-		}
-	}
-
-	if create {
-		consoleLog.Info("Creating Service for console dynamic plugin")
-		err := kclient.Create(context.TODO(), service, &client.CreateOptions{})
-		if err != nil {
-			return err
-		}
-	} else {
-		consoleLog.Info("Updating Service for console dynamic plugin")
-		service.DeepCopyInto(&found)
-		err := kclient.Update(context.TODO(), &found, &client.UpdateOptions{})
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func getService(name string, namespace string) corev1.Service {
 	service := corev1.Service{
 		TypeMeta: metav1.TypeMeta{
@@ -354,38 +359,6 @@ func getService(name string, namespace string) corev1.Service {
 	return service
 }
 
-// Create or update the ConsolePlugin for a console dynamic plugin
-func createOrUpdateConsolePlugin(kclient client.Client, consolePlugin *consolev1.ConsolePlugin) error {
-	var found consolev1.ConsolePlugin
-	key := client.ObjectKeyFromObject(consolePlugin)
-	err := kclient.Get(context.TODO(), key, &found, &client.GetOptions{})
-	create := false
-	if err != nil {
-		if kerrors.IsNotFound(err) {
-			create = true
-		} else {
-			return err
-		}
-	}
-
-	if create {
-		consoleLog.Info("Creating ConsolePlugin for console dynamic plugin")
-		err := kclient.Create(context.TODO(), consolePlugin, &client.CreateOptions{})
-		if err != nil {
-			return err
-		}
-	} else {
-		consoleLog.Info("Updating ConsolePlugin for console dynamic plugin")
-		consolePlugin.DeepCopyInto(&found)
-		err := kclient.Update(context.TODO(), &found, &client.UpdateOptions{})
-		if err != nil {
-			return err
-		}
-	}
-
-	return nil
-}
-
 func getConsolePlugin(name string, namespace string) consolev1.ConsolePlugin {
 	consolePlugin := consolev1.ConsolePlugin{
 		TypeMeta: metav1.TypeMeta{
@@ -411,5 +384,28 @@ func getConsolePlugin(name string, namespace string) consolev1.ConsolePlugin {
 			},
 		},
 	}
+	if features.Enabled(features.ConsolePluginBackendProxy) {
+		// The plugin reaches the operator backend API at
+		// /api/proxy/plugin/<name>/backend/ with the user's token
+		consolePlugin.Spec.Proxy = getBackendProxy(namespace)
+	}
 	return consolePlugin
 }
+
+// Return the ConsolePlugin proxy to the operator backend API
+func getBackendProxy(namespace string) []consolev1.ConsolePluginProxy {
+	return []consolev1.ConsolePluginProxy{
+		{
+			Alias:         "backend",
+			Authorization: consolev1.UserToken,
+			Endpoint: consolev1.ConsolePluginProxyEndpoint{
+				Type: consolev1.ProxyTypeService,
+				Service: &consolev1.ConsolePluginProxyServiceConfig{
+					Name:      config.BackendServiceName,
+					Namespace: namespace,
+					Port:      config.BackendPort,
+				},
+			},
+		},
+	}
+}
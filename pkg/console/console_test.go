@@ -0,0 +1,139 @@
+package console
+
+import (
+	"context"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/yaml"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/features"
+)
+
+// Run `go test ./pkg/console -update` to regenerate golden files after
+// changing a manifest builder.
+var update = flag.Bool("update", false, "update golden files")
+
+// Compare obj, marshalled to YAML, against testdata/<golden>
+func assertGolden(t *testing.T, golden string, obj interface{}) {
+	t.Helper()
+	got, err := yaml.Marshal(obj)
+	if err != nil {
+		t.Fatalf("Unable to marshal %s: %v", golden, err)
+	}
+
+	path := filepath.Join("testdata", golden)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("Unable to update %s: %v", path, err)
+		}
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Unable to read %s: %v", path, err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s does not match golden file\n--- got:\n%s\n--- want:\n%s", golden, got, want)
+	}
+}
+
+func TestManifestsDefault(t *testing.T) {
+	t.Setenv("RELATED_IMAGE_CONSOLE_PLUGIN", "")
+	name := getPluginName()
+	namespace := "cat-facts-operator"
+	options := DefaultPluginOptions()
+
+	assertGolden(t, "deployment.yaml", getDeployment(name, namespace, options))
+	assertGolden(t, "service.yaml", getService(name, namespace))
+	assertGolden(t, "consoleplugin.yaml", getConsolePlugin(name, namespace))
+}
+
+func TestManifestsHardened(t *testing.T) {
+	t.Setenv("RELATED_IMAGE_CONSOLE_PLUGIN", "")
+	name := getPluginName()
+	namespace := "cat-facts-operator"
+	options := DefaultPluginOptions()
+	options.PodDisruptionBudget = true
+	options.NetworkPolicy = true
+	options.TopologySpread = true
+	options.Autoscaling.Enabled = true
+
+	assertGolden(t, "deployment_hardened.yaml", getDeployment(name, namespace, options))
+	assertGolden(t, "poddisruptionbudget.yaml", getPodDisruptionBudget(name, namespace))
+	assertGolden(t, "networkpolicy.yaml", getNetworkPolicy(name, namespace))
+	assertGolden(t, "horizontalpodautoscaler.yaml", getHorizontalPodAutoscaler(name, namespace, options.Autoscaling))
+}
+
+func TestCreateOrUpdateKeepsAutoscaledReplicas(t *testing.T) {
+	name, namespace := getPluginName(), "cat-facts-operator"
+	options := DefaultPluginOptions()
+	options.Autoscaling.Enabled = true
+
+	// The HPA already scaled the plugin
+	scaled := getDeployment(name, namespace, options)
+	scaled.Spec.Replicas = int32Ptr(3)
+	kclient := newFakeClient(&scaled)
+
+	deployment := getDeployment(name, namespace, options)
+	if err := createOrUpdate(kclient, &deployment); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var found appsv1.Deployment
+	if err := kclient.Get(context.TODO(), client.ObjectKeyFromObject(&deployment), &found); err != nil {
+		t.Fatalf("Unable to get Deployment: %v", err)
+	}
+	if found.Spec.Replicas == nil || *found.Spec.Replicas != 3 {
+		t.Errorf("Expected the HPA's 3 replicas to be kept, got %v", found.Spec.Replicas)
+	}
+
+	// Without autoscaling the configured replicas win
+	options.Autoscaling.Enabled = false
+	deployment = getDeployment(name, namespace, options)
+	if err := createOrUpdate(kclient, &deployment); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := kclient.Get(context.TODO(), client.ObjectKeyFromObject(&deployment), &found); err != nil {
+		t.Fatalf("Unable to get Deployment: %v", err)
+	}
+	if *found.Spec.Replicas != options.Replicas {
+		t.Errorf("Expected %d replicas, got %d", options.Replicas, *found.Spec.Replicas)
+	}
+}
+
+func TestBackendProxyFeatureGate(t *testing.T) {
+	if err := features.DefaultFeatureGate.Set("ConsolePluginBackendProxy=false"); err != nil {
+		t.Fatal(err)
+	}
+	defer features.DefaultFeatureGate.Set("ConsolePluginBackendProxy=true")
+
+	plugin := getConsolePlugin(getPluginName(), "cat-facts-operator")
+	if len(plugin.Spec.Proxy) != 0 {
+		t.Errorf("Expected no proxy when ConsolePluginBackendProxy is disabled, got %+v", plugin.Spec.Proxy)
+	}
+}
+
+func TestPluginImage(t *testing.T) {
+	t.Setenv("RELATED_IMAGE_CONSOLE_PLUGIN", "")
+	image := getPluginImage()
+	if image != "quay.io/ryanmillerc/cat-facts-operator-console-plugin:v0.0.0-dev" {
+		t.Errorf("Expected tagged image, got %s", image)
+	}
+	if policy := getImagePullPolicy(image); policy != "Always" {
+		t.Errorf("Expected pull policy Always for %s, got %s", image, policy)
+	}
+
+	digest := "quay.io/ryanmillerc/cat-facts-operator-console-plugin@sha256:0123456789abcdef0123456789abcdef0123456789abcdef0123456789abcdef"
+	t.Setenv("RELATED_IMAGE_CONSOLE_PLUGIN", digest)
+	image = getPluginImage()
+	if image != digest {
+		t.Errorf("Expected %s, got %s", digest, image)
+	}
+	if policy := getImagePullPolicy(image); policy != "IfNotPresent" {
+		t.Errorf("Expected pull policy IfNotPresent for %s, got %s", image, policy)
+	}
+}
@@ -0,0 +1,87 @@
+/*
+Options for the OpenShift Dynamic Console plugin resources.
+*/
+
+package console
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/config"
+)
+
+// PluginOptions configures how the console plugin is deployed. The defaults
+// match a small single replica deployment. Production clusters can enable the
+// hardening resources.
+type PluginOptions struct {
+	// Container image for the plugin. Images referenced by digest are pulled
+	// with IfNotPresent, tags with Always.
+	Image string
+
+	// Number of plugin replicas. Ignored when autoscaling is enabled.
+	Replicas int32
+
+	// Create a PodDisruptionBudget so node drains don't take down every
+	// plugin replica at once.
+	PodDisruptionBudget bool
+
+	// Create a NetworkPolicy that only allows ingress from the console
+	// namespace (config.ConsoleNamespace).
+	NetworkPolicy bool
+
+	// Spread plugin pods across nodes and zones.
+	TopologySpread bool
+
+	// Manage a HorizontalPodAutoscaler for the plugin Deployment.
+	Autoscaling AutoscalingOptions
+}
+
+// AutoscalingOptions configures the plugin HorizontalPodAutoscaler.
+type AutoscalingOptions struct {
+	Enabled bool
+
+	MinReplicas int32
+	MaxReplicas int32
+
+	// Target average CPU utilization as a percentage of requested CPU.
+	TargetCPUUtilization int32
+}
+
+// Return the default PluginOptions. Hardening resources are disabled.
+func DefaultPluginOptions() PluginOptions {
+	return PluginOptions{
+		Image:    getPluginImage(),
+		Replicas: 1,
+		Autoscaling: AutoscalingOptions{
+			MinReplicas:          2,
+			MaxReplicas:          4,
+			TargetCPUUtilization: 80,
+		},
+	}
+}
+
+// Return the console plugin container image.
+//
+// RELATED_IMAGE_CONSOLE_PLUGIN is preferred. OLM resolves RELATED_IMAGE_*
+// environment variables to digests and lists them as related images, which is
+// needed for disconnected mirroring. Otherwise config.ConsolePluginImage
+// tagged with config.Version is used.
+func getPluginImage() string {
+	if image, ok := os.LookupEnv("RELATED_IMAGE_CONSOLE_PLUGIN"); ok && image != "" {
+		return image
+	}
+	return fmt.Sprintf("%s:%s", config.ConsolePluginImage, config.Version)
+}
+
+// Return the pull policy for an image. Digests can't change, so they only
+// need to be pulled when missing.
+func getImagePullPolicy(image string) corev1.PullPolicy {
+	if strings.Contains(image, "@") {
+		return corev1.PullIfNotPresent
+	}
+	return corev1.PullAlways
+}
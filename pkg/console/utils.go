@@ -5,8 +5,13 @@ struct fields on k8s types.
 
 package console
 
+import corev1 "k8s.io/api/core/v1"
+
 // Return a new int32 pointer with the passed value
 func int32Ptr(i int32) *int32 { return &i }
 
 // Return a new bool pointer with the passed value
 func boolPtr(b bool) *bool { return &b }
+
+// Return a new Protocol pointer with the passed value
+func protocolPtr(p corev1.Protocol) *corev1.Protocol { return &p }
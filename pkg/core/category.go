@@ -0,0 +1,120 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"strings"
+	"unicode"
+)
+
+// Categories facts are classified into
+const (
+	CategoryAnatomy  = "anatomy"
+	CategoryBehavior = "behavior"
+	CategoryBreeds   = "breeds"
+	CategoryDiet     = "diet"
+	CategoryHealth   = "health"
+	CategoryHistory  = "history"
+	CategorySenses   = "senses"
+
+	// Category of facts without any keywords of the other categories
+	CategoryGeneral = "general"
+)
+
+// Keywords of each category, matched against the words of a fact. A keyword
+// ending in "*" matches words starting with it.
+var categoryKeywords = map[string][]string{
+	CategoryAnatomy: {
+		"bone*", "body", "bodies", "claw*", "collarbone*", "ear", "ears", "fur", "heart*",
+		"jaw*", "muscle*", "nose*", "paw*", "skeleton*", "spine*", "tail*", "teeth",
+		"tongue*", "tooth", "toe*", "vertebra*", "whisker*",
+	},
+	CategoryBehavior: {
+		"groom*", "hunt*", "jump*", "knead*", "meow*", "play*", "purr*", "scratch*",
+		"sleep*", "slept", "territor*", "hiss*", "nap*", "climb*", "chirp*",
+	},
+	CategoryBreeds: {
+		"abyssinian*", "bengal*", "breed*", "maine", "manx", "persian*", "ragdoll*",
+		"siamese", "sphynx", "tabby", "tabbies",
+	},
+	CategoryDiet: {
+		"diet*", "drink*", "eat*", "ate", "food*", "meal*", "meat*", "milk", "mice",
+		"mouse", "prey", "water", "catnip",
+	},
+	CategoryHealth: {
+		"allerg*", "disease*", "health*", "ill", "illness*", "lifespan", "obes*",
+		"sick*", "vaccin*", "vet", "vets", "veterinar*", "weight", "age", "aged", "old",
+	},
+	CategoryHistory: {
+		"ancient", "century", "centuries", "domesticat*", "egypt*", "histor*",
+		"mummif*", "pharaoh*", "roman*", "viking*", "year*", "bc",
+	},
+	CategorySenses: {
+		"hear", "hearing", "heard", "see", "sees", "seeing", "sight", "smell*",
+		"sens*", "taste*", "vision", "night", "color*", "colour*",
+	},
+}
+
+// Return all categories facts are classified into
+func Categories() []string {
+	return []string{
+		CategoryAnatomy,
+		CategoryBehavior,
+		CategoryBreeds,
+		CategoryDiet,
+		CategoryHealth,
+		CategoryHistory,
+		CategorySenses,
+		CategoryGeneral,
+	}
+}
+
+// Return the category of a fact: the category with the most keywords in the
+// fact, or CategoryGeneral if it has none. Ties go to the category listed
+// first by Categories.
+func Classify(fact string) string {
+	words := strings.FieldsFunc(strings.ToLower(fact), func(r rune) bool {
+		return !unicode.IsLetter(r)
+	})
+
+	best, bestScore := CategoryGeneral, 0
+	for _, category := range Categories() {
+		score := 0
+		for _, word := range words {
+			if matchesAnyKeyword(word, categoryKeywords[category]) {
+				score++
+			}
+		}
+		if score > bestScore {
+			best, bestScore = category, score
+		}
+	}
+	return best
+}
+
+func matchesAnyKeyword(word string, keywords []string) bool {
+	for _, keyword := range keywords {
+		if prefix, ok := strings.CutSuffix(keyword, "*"); ok {
+			if strings.HasPrefix(word, prefix) {
+				return true
+			}
+		} else if word == keyword {
+			return true
+		}
+	}
+	return false
+}
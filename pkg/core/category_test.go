@@ -0,0 +1,43 @@
+package core
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestClassify(t *testing.T) {
+	tests := map[string]string{
+		"Cats have 230 bones in their body, while humans have 206.":                  CategoryAnatomy,
+		"A cat's whiskers are about as wide as its body.":                            CategoryAnatomy,
+		"Cats sleep for 12 to 16 hours a day.":                                       CategoryBehavior,
+		"Cats purr when they're content, and sometimes when they're hurt.":           CategoryBehavior,
+		"The Maine Coon is one of the largest domestic breeds.":                      CategoryBreeds,
+		"Most cats are lactose intolerant and shouldn't drink milk.":                 CategoryDiet,
+		"Obesity is the most common health problem in cats.":                         CategoryHealth,
+		"Ancient Egyptians mummified their cats.":                                    CategoryHistory,
+		"Cats can see in light six times dimmer than humans need, thanks to vision.": CategorySenses,
+		"Cats are cool!": CategoryGeneral,
+		"":               CategoryGeneral,
+	}
+	for fact, want := range tests {
+		if got := Classify(fact); got != want {
+			t.Errorf("Classify(%q) = %s, want %s", fact, got, want)
+		}
+	}
+}
+
+func TestCategoriesHaveKeywords(t *testing.T) {
+	for _, category := range Categories() {
+		if category == CategoryGeneral {
+			continue
+		}
+		if len(categoryKeywords[category]) == 0 {
+			t.Errorf("Category %s has no keywords", category)
+		}
+	}
+	for category := range categoryKeywords {
+		if !slices.Contains(Categories(), category) {
+			t.Errorf("Keywords for %s, which isn't in Categories()", category)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Shortest interval between advancing a deck's current member
+const MinAdvanceInterval = time.Minute
+
+// Returned by ProcessCatFactDeck when spec.selector isn't a valid label
+// selector. Retrying won't help until the deck is changed.
+var ErrInvalidSelector = errors.New("invalid selector")
+
+// Resolve a CatFactDeck's members from the CatFacts in its namespace into its
+// status, and advance its current member if it's due. Returns the time until
+// it's due to advance again, or zero if it doesn't advance.
+func ProcessCatFactDeck(deck *tacomoev1beta1.CatFactDeck, catFacts []tacomoev1beta1.CatFact, now time.Time) (time.Duration, error) {
+	members, missing, err := DeckMembers(deck, catFacts)
+	if err != nil {
+		return 0, err
+	}
+
+	status := &deck.Status
+	status.ObservedGeneration = deck.Generation
+	status.MissingMembers = missing
+	status.Count = int32(len(members))
+	status.Members = members
+	if len(members) == 0 {
+		status.Current = 0
+		status.CurrentName = ""
+		return 0, nil
+	}
+
+	// Stay on the current CatFact if it's still in the deck. If it was
+	// removed, the one that took its place is current.
+	current := slices.Index(members, status.CurrentName)
+	if current < 0 {
+		current = min(max(int(status.Current), 0), len(members)-1)
+	}
+
+	var advanceAfter time.Duration
+	if deck.Spec.AdvanceInterval != nil {
+		interval := max(deck.Spec.AdvanceInterval.Duration, MinAdvanceInterval)
+		if status.LastAdvanceTime == nil {
+			status.LastAdvanceTime = &metav1.Time{Time: now}
+			advanceAfter = interval
+		} else if due := status.LastAdvanceTime.Add(interval); !now.Before(due) {
+			current = (current + 1) % len(members)
+			status.LastAdvanceTime = &metav1.Time{Time: now}
+			advanceAfter = interval
+		} else {
+			advanceAfter = due.Sub(now)
+		}
+	}
+	status.Current = int32(current)
+	status.CurrentName = members[current]
+	return advanceAfter, nil
+}
+
+// Return the names of a deck's CatFacts in order: spec.members that exist,
+// followed by CatFacts matching spec.selector ordered by name. Also returns
+// spec.members that don't exist.
+func DeckMembers(deck *tacomoev1beta1.CatFactDeck, catFacts []tacomoev1beta1.CatFact) (members []string, missing []string, err error) {
+	byName := map[string]*tacomoev1beta1.CatFact{}
+	for i := range catFacts {
+		byName[catFacts[i].Name] = &catFacts[i]
+	}
+
+	for _, member := range deck.Spec.Members {
+		if _, ok := byName[member.Name]; ok {
+			members = append(members, member.Name)
+		} else {
+			missing = append(missing, member.Name)
+		}
+	}
+
+	if deck.Spec.Selector != nil {
+		selector, err := metav1.LabelSelectorAsSelector(deck.Spec.Selector)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSelector, err)
+		}
+		var selected []string
+		for name, catFact := range byName {
+			if selector.Matches(labels.Set(catFact.Labels)) && !slices.Contains(members, name) {
+				selected = append(selected, name)
+			}
+		}
+		slices.Sort(selected)
+		members = append(members, selected...)
+	}
+	return members, missing, nil
+}
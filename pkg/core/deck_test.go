@@ -0,0 +1,118 @@
+package core
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func newDeckCatFact(name string, labels map[string]string) tacomoev1beta1.CatFact {
+	return tacomoev1beta1.CatFact{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func TestDeckMembers(t *testing.T) {
+	trivia := map[string]string{"deck": "trivia"}
+	catFacts := []tacomoev1beta1.CatFact{
+		newDeckCatFact("zebra", trivia),
+		newDeckCatFact("first", nil),
+		newDeckCatFact("apple", trivia),
+		newDeckCatFact("second", trivia),
+		newDeckCatFact("unrelated", nil),
+	}
+	deck := &tacomoev1beta1.CatFactDeck{
+		Spec: tacomoev1beta1.CatFactDeckSpec{
+			Members: []tacomoev1beta1.DeckMember{{Name: "second"}, {Name: "gone"}, {Name: "first"}},
+			Selector: &metav1.LabelSelector{
+				MatchLabels: trivia,
+			},
+		},
+	}
+
+	members, missing, err := DeckMembers(deck, catFacts)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if want := []string{"second", "first", "apple", "zebra"}; !slices.Equal(members, want) {
+		t.Errorf("Expected members %v, got %v", want, members)
+	}
+	if want := []string{"gone"}; !slices.Equal(missing, want) {
+		t.Errorf("Expected missing members %v, got %v", want, missing)
+	}
+
+	deck.Spec.Selector.MatchLabels = map[string]string{"deck": "not a valid value!"}
+	if _, _, err := DeckMembers(deck, catFacts); !errors.Is(err, ErrInvalidSelector) {
+		t.Errorf("Expected ErrInvalidSelector, got %v", err)
+	}
+}
+
+func TestProcessCatFactDeckAdvance(t *testing.T) {
+	catFacts := []tacomoev1beta1.CatFact{newDeckCatFact("a", nil), newDeckCatFact("b", nil), newDeckCatFact("c", nil)}
+	deck := &tacomoev1beta1.CatFactDeck{
+		ObjectMeta: metav1.ObjectMeta{Generation: 4},
+		Spec: tacomoev1beta1.CatFactDeckSpec{
+			Members:         []tacomoev1beta1.DeckMember{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+			AdvanceInterval: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+	now := time.Now()
+
+	after, err := ProcessCatFactDeck(deck, catFacts, now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deck.Status.CurrentName != "a" || deck.Status.Count != 3 || after != time.Hour || deck.Status.ObservedGeneration != 4 {
+		t.Errorf("Expected the deck to start on a for an hour, got %+v after %s", deck.Status, after)
+	}
+
+	after, _ = ProcessCatFactDeck(deck, catFacts, now.Add(20*time.Minute))
+	if deck.Status.CurrentName != "a" || after != 40*time.Minute {
+		t.Errorf("Expected the deck to stay on a for 40m, got %s after %s", deck.Status.CurrentName, after)
+	}
+
+	for _, want := range []string{"b", "c", "a"} {
+		now = now.Add(time.Hour)
+		if _, err := ProcessCatFactDeck(deck, catFacts, now); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if deck.Status.CurrentName != want {
+			t.Errorf("Expected the deck to advance to %s, got %s", want, deck.Status.CurrentName)
+		}
+	}
+}
+
+func TestProcessCatFactDeckMembersChanged(t *testing.T) {
+	deck := &tacomoev1beta1.CatFactDeck{
+		Spec: tacomoev1beta1.CatFactDeckSpec{
+			Members: []tacomoev1beta1.DeckMember{{Name: "a"}, {Name: "b"}, {Name: "c"}},
+		},
+		Status: tacomoev1beta1.CatFactDeckStatus{Current: 1, CurrentName: "b"},
+	}
+
+	// b moves when a is removed
+	if _, err := ProcessCatFactDeck(deck, []tacomoev1beta1.CatFact{newDeckCatFact("b", nil), newDeckCatFact("c", nil)}, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deck.Status.Current != 0 || deck.Status.CurrentName != "b" {
+		t.Errorf("Expected b to stay current, got %+v", deck.Status)
+	}
+
+	// The one that takes b's place is current when b is removed
+	if _, err := ProcessCatFactDeck(deck, []tacomoev1beta1.CatFact{newDeckCatFact("a", nil), newDeckCatFact("c", nil)}, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deck.Status.CurrentName != "a" || !slices.Equal(deck.Status.MissingMembers, []string{"b"}) {
+		t.Errorf("Expected a to be current with b missing, got %+v", deck.Status)
+	}
+
+	if _, err := ProcessCatFactDeck(deck, nil, time.Now()); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if deck.Status.Count != 0 || deck.Status.CurrentName != "" {
+		t.Errorf("Expected an empty deck, got %+v", deck.Status)
+	}
+}
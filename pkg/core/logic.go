@@ -17,47 +17,179 @@ limitations under the License.
 package core
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"math/rand"
 	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
 
-	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
 )
 
-func ProcessCatFact(instance *tacomoev1alpha1.CatFact) error {
-	if len(instance.Spec.Fact) == 0 {
-		err := GenerateFact(instance)
-		if err != nil {
-			return err
+// What ProcessCatFact did to a CatFact
+type Result struct {
+	// A fact was generated because spec.fact was empty or the fact was due
+	// to be refreshed
+	FactGenerated bool
+
+	// Error from the fact provider. If set, the placeholder fact was used.
+	ProviderErr error
+
+	// Error translating the fact into spec.locale. If set, the untranslated
+	// fact was used.
+	TranslationErr error
+
+	// Time until translating the fact should be retried. Zero if it isn't
+	// waiting to be retried.
+	RetryTranslationAfter time.Duration
+
+	// An iconName was generated because spec.iconName was empty
+	IconGenerated bool
+
+	// Time until the generated fact should be refreshed. Zero if it's never
+	// refreshed.
+	RefreshAfter time.Duration
+}
+
+// Resolve a CatFact's or ClusterCatFact's fact and iconName into its status.
+// spec.fact and spec.iconName are used if they're set, otherwise they're
+// generated. The fact is translated into spec.locale and the iconName is
+// validated. While spec.locked is set, a resolved fact and iconName are kept
+// as they are.
+func ProcessCatFact(ctx context.Context, instance tacomoev1beta1.CatFactObject) (result Result, err error) {
+	ctx, span := tracing.Start(ctx, "ProcessCatFact")
+	defer func() {
+		span.SetAttributes(
+			attribute.Bool("catfact.fact_generated", result.FactGenerated),
+			attribute.Bool("catfact.icon_generated", result.IconGenerated),
+		)
+		tracing.RecordError(span, err)
+		span.End()
+	}()
+
+	now := time.Now()
+	spec := instance.GetCatFactSpec()
+	status := instance.GetCatFactStatus()
+	// spec.fact and spec.iconName can't change while locked, but may have
+	// changed in the same update that locked the CatFact
+	factLocked := spec.Locked && len(status.Fact) > 0 && (len(spec.Fact) == 0 || originalFact(status) == spec.Fact)
+	iconLocked := spec.Locked && len(status.IconName) > 0 && (len(spec.IconName) == 0 || status.IconName == spec.IconName)
+	if factLocked {
+		// Not refreshed, translated, or replaced
+	} else if len(spec.Fact) > 0 {
+		if originalFact(status) != spec.Fact {
+			status.Fact, status.OriginalFact, status.Locale = spec.Fact, "", ""
 		}
+		status.Category = Classify(spec.Fact)
+		status.Source = ""
+		status.LastRefreshTime = nil
+		status.Moderation = nil
+	} else if len(status.Fact) == 0 || len(status.Source) == 0 || refreshAfter(instance, now) == 0 {
+		err := GenerateFact(ctx, instance)
+		if errors.Is(err, ErrUnknownFactSource) {
+			return result, err
+		}
+		result.FactGenerated = true
+		result.ProviderErr = err
+	} else if len(status.Category) == 0 {
+		status.Category = Classify(originalFact(status))
+	}
+	if !factLocked {
+		result.TranslationErr = TranslateFact(ctx, instance, now)
+		result.RetryTranslationAfter = TranslationRetryAfter(instance, now)
+	}
+	if len(spec.Fact) == 0 && !spec.Locked {
+		result.RefreshAfter = max(refreshAfter(instance, time.Now()), 0)
 	}
 
-	if len(instance.Spec.IconName) == 0 {
-		err := GenerateIconName(instance)
-		if err != nil {
-			return err
+	if iconLocked {
+		// Kept even if it's no longer allowed
+	} else if len(spec.IconName) == 0 {
+		if len(status.IconName) == 0 || !isValidIconName(status.IconName) {
+			err := GenerateIconName(instance)
+			if err != nil {
+				return result, err
+			}
+			result.IconGenerated = true
 		}
-	} else if !isValidIconName(instance.Spec.IconName) {
-		return fmt.Errorf("not a valid iconName %s", instance.Spec.IconName)
+	} else if !isValidIconName(spec.IconName) {
+		metrics.InvalidIcons.Inc()
+		return result, fmt.Errorf("%w %s", ErrInvalidIconName, spec.IconName)
+	} else {
+		status.IconName = spec.IconName
 	}
 
-	return nil
+	status.ObservedGeneration = instance.GetGeneration()
+	return result, nil
 }
 
+// Shortest interval between refreshing generated facts
+const MinRefreshInterval = time.Minute
+
+// Return the time until a CatFact's generated fact should be refreshed, or
+// zero if it's due. Returns -1 if it's never refreshed.
+func refreshAfter(instance tacomoev1beta1.CatFactObject, now time.Time) time.Duration {
+	policy := instance.GetCatFactSpec().RefreshPolicy
+	lastRefresh := instance.GetCatFactStatus().LastRefreshTime
+	if policy == nil || lastRefresh == nil {
+		return -1
+	}
+	interval := max(policy.Interval.Duration, MinRefreshInterval)
+	return max(lastRefresh.Add(interval).Sub(now), 0)
+}
+
+// Returned by ProcessCatFact when spec.iconName isn't one of ValidIconNames.
+// Retrying won't help until the CatFact is changed.
+var ErrInvalidIconName = errors.New("not a valid iconName")
+
+// Returned by ProcessCatFact when spec.sourceRef doesn't name a configured
+// fact source. Retrying won't help until the CatFact or config is changed.
+var ErrUnknownFactSource = errors.New("unknown fact source")
+
+// Fact used when the fact provider can't be reached
+const PlaceholderFact = "Cats are cool!"
+
 type CatFactNinjaAPIResponse struct {
 	Fact   string `json:"fact"`
 	Length int    `json:"length"`
 }
 
+// How long a request to a fact API, moderation webhook, or translate endpoint
+// may take, so a hung endpoint can't block a reconcile worker
+const RequestTimeout = 10 * time.Second
+
+// Client for fact APIs, moderation webhooks, and translate endpoints.
+// Requests are traced and carry the trace context.
+var factClient = &http.Client{Transport: tracing.Transport(nil), Timeout: RequestTimeout}
+
 var GetFactFromURL = getFactFromURL // Set function as variable for easier testing
-func getFactFromURL(requestURL string) (string, error) {
-	res, err := http.Get(requestURL)
+func getFactFromURL(ctx context.Context, requestURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
 		return "", err
 	}
+	res, err := factClient.Do(req)
+	if err != nil {
+		metrics.ObserveFactAPIError()
+		return "", err
+	}
 	defer res.Body.Close() // Wait for API response
+	metrics.ObserveFactAPIResponse(res.StatusCode)
+
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %s", requestURL, res.Status)
+	}
 
 	body, err := io.ReadAll(res.Body) // response body is []byte
 	if err != nil {
@@ -71,26 +203,113 @@ func getFactFromURL(requestURL string) (string, error) {
 	return apiResponse.Fact, err
 }
 
-func GenerateFact(instance *tacomoev1alpha1.CatFact) error {
-	requestURL := "https://catfact.ninja/fact"
-	fact, err := GetFactFromURL(requestURL)
+// Set status.fact from the CatFact's fact source, or DefaultProvider() if it
+// doesn't have one. The fact is requested in spec.category if the source
+// supports it, otherwise its category is derived from its text. Facts
+// rejected by the moderators are fetched again, up to MaxModerationAttempts
+// times, before the placeholder fact is used. If the provider fails, a
+// placeholder fact is set and the provider error is returned.
+func GenerateFact(ctx context.Context, instance tacomoev1beta1.CatFactObject) error {
+	spec := instance.GetCatFactSpec()
+	provider := DefaultProvider()
+	if ref := spec.SourceRef; ref != nil {
+		var ok bool
+		if provider, ok = FactProviderByName(ref.Name); !ok {
+			return fmt.Errorf("%w %s", ErrUnknownFactSource, ref.Name)
+		}
+	}
+	start := time.Now()
+	var fact string
+	var inCategory bool
+	var err error
+	var moderation *tacomoev1beta1.ModerationStatus
+	for attempt := 0; attempt < MaxModerationAttempts; attempt++ {
+		fact, inCategory, err = fetchFactWithSpan(ctx, provider, spec.Category)
+		if err != nil || len(Moderators()) == 0 {
+			break
+		}
+		if moderation == nil {
+			moderation = &tacomoev1beta1.ModerationStatus{}
+		}
+		rejectedBy, reason := Moderate(ctx, fact)
+		if rejectedBy == "" {
+			moderation.Decision = tacomoev1beta1.ModerationAllowed
+			break
+		}
+		moderation.Decision = tacomoev1beta1.ModerationReplaced
+		moderation.Rejected++
+		moderation.Moderator, moderation.Reason = rejectedBy, reason
+	}
 	if err != nil {
-		// If there's an error getting a fact from catfacts.ninja, use this placeholder fact.
-		// TODO: Should also log here
-		fact = "Cats are cool!"
+		moderation = nil
 	}
-	instance.Spec.Fact = fact
-	return nil
+
+	source := provider.Name()
+	if err != nil || (moderation != nil && moderation.Decision == tacomoev1beta1.ModerationReplaced) {
+		// If there's an error getting a fact from catfacts.ninja, or every
+		// fact was rejected, use this placeholder fact.
+		fact, inCategory = PlaceholderFact, false
+		source = metrics.SourcePlaceholder
+		reason := metrics.FallbackModerated
+		if err != nil {
+			reason = metrics.FallbackProviderError
+		}
+		metrics.FallbackFacts.WithLabelValues(reason).Inc()
+	}
+	metrics.FactsGenerated.WithLabelValues(source).Inc()
+	status := instance.GetCatFactStatus()
+	status.Fact, status.OriginalFact, status.Locale = fact, "", ""
+	status.Category = Classify(fact)
+	if inCategory {
+		status.Category = spec.Category
+	}
+	status.Source = source
+	status.LastRefreshTime = &metav1.Time{Time: start}
+	status.Moderation = moderation
+	return err
+}
+
+// Get a fact from provider in a traced span, see fetchFact
+func fetchFactWithSpan(ctx context.Context, provider FactProvider, category string) (string, bool, error) {
+	ctx, span := tracing.Start(ctx, "FactProvider.Fact", trace.WithAttributes(
+		attribute.String("catfact.provider", provider.Name()),
+	))
+	defer span.End()
+
+	start := time.Now()
+	fact, inCategory, err := fetchFact(ctx, provider, category)
+	metrics.ProviderLatency.WithLabelValues(provider.Name()).Observe(time.Since(start).Seconds())
+	tracing.RecordError(span, err)
+	return fact, inCategory, err
 }
 
-// Return a random IconName
-func GenerateIconName(instance *tacomoev1alpha1.CatFact) error {
-	rint := randInt(1, len(getValidIconNames()))
-	instance.Spec.IconName = getValidIconNames()[rint]
+// Get a fact from provider, in category if it's set and the provider supports
+// it. Returns true if the fact is in category.
+func fetchFact(ctx context.Context, provider FactProvider, category string) (string, bool, error) {
+	if categoryProvider, ok := provider.(CategoryFactProvider); ok && category != "" {
+		fact, err := categoryProvider.FactInCategory(ctx, category)
+		if !errors.Is(err, ErrCategoryUnsupported) {
+			return fact, err == nil, err
+		}
+	}
+	fact, err := provider.Fact(ctx)
+	return fact, false, err
+}
+
+// Set a random IconName in status.iconName
+func GenerateIconName(instance tacomoev1beta1.CatFactObject) error {
+	instance.GetCatFactStatus().IconName = RandomIconName()
 	return nil
 }
 
-func getValidIconNames() []string {
+// Return a random allowed IconName
+func RandomIconName() string {
+	names := AllowedIconNames()
+	return names[rand.Intn(len(names))]
+}
+
+// Return all valid IconNames. The console plugin has an image for each one.
+func ValidIconNames() []string {
 	return []string{
 		"Grinning",
 		"Smiling",
@@ -104,17 +323,29 @@ func getValidIconNames() []string {
 	}
 }
 
-// Return a random int between two numbers
-func randInt(min int, max int) int {
-	return min + rand.Intn(max-min)
+// IconNames CatFacts may use. Swapped atomically so the icon policy can be
+// reloaded while CatFacts are being reconciled.
+var allowedIconNames atomic.Pointer[[]string]
+
+// Return the IconNames CatFacts may use. Defaults to ValidIconNames.
+func AllowedIconNames() []string {
+	if names := allowedIconNames.Load(); names != nil {
+		return *names
+	}
+	return ValidIconNames()
 }
 
-// Validate that a given IconName is valid
-func isValidIconName(iconName string) bool {
-	for _, name := range getValidIconNames() {
-		if iconName == name {
-			return true
-		}
+// Restrict the IconNames CatFacts may use. Names must be a subset of
+// ValidIconNames. An empty list allows all valid IconNames.
+func SetAllowedIconNames(names []string) {
+	if len(names) == 0 {
+		allowedIconNames.Store(nil)
+		return
 	}
-	return false
+	allowedIconNames.Store(&names)
+}
+
+// Validate that a given IconName is allowed
+func isValidIconName(iconName string) bool {
+	return slices.Contains(AllowedIconNames(), iconName)
 }
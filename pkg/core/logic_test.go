@@ -1,22 +1,34 @@
 package core
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
-	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace/noop"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
 )
 
 func TestGenerateFact(t *testing.T) {
 	// Monkey patch GetFactFromURL
-	GetFactFromURL = func(string) (string, error) {
+	GetFactFromURL = func(context.Context, string) (string, error) {
 		return "Cats are cool!", nil
 	}
-	instance := &tacomoev1alpha1.CatFact{}
-	GenerateFact(instance)
-	if instance.Spec.Fact != "Cats are cool!" {
-		t.Fatalf(`instance.Spec.Fact is "%s", want match for "Cats are cool!"`, instance.Spec.Fact)
+	instance := &tacomoev1beta1.CatFact{}
+	GenerateFact(context.Background(), instance)
+	if instance.Status.Fact != "Cats are cool!" {
+		t.Fatalf(`instance.Status.Fact is "%s", want match for "Cats are cool!"`, instance.Status.Fact)
 	}
 }
 
@@ -30,7 +42,7 @@ func TestGetFactFromURL(t *testing.T) {
 	}))
 	defer server.Close()
 
-	value, _ := getFactFromURL(server.URL + "/fact")
+	value, _ := getFactFromURL(context.Background(), server.URL+"/fact")
 	if value != "Cats are cool!" {
 		t.Errorf("Expected 'Cats are cool!', got %s", value)
 	}
@@ -48,16 +60,16 @@ func TestGenerateIconName(t *testing.T) {
 		"Crying",
 		"Pouting",
 	}
-	instance := &tacomoev1alpha1.CatFact{}
+	instance := &tacomoev1beta1.CatFact{}
 	GenerateIconName(instance)
 	testPassed := false
 	for _, name := range validIconNames {
-		if name == instance.Spec.IconName {
+		if name == instance.Status.IconName {
 			testPassed = true
 		}
 	}
 	if !testPassed {
-		t.Errorf("Expected %s to be a valid IconName", instance.Spec.IconName)
+		t.Errorf("Expected %s to be a valid IconName", instance.Status.IconName)
 	}
 }
 
@@ -69,3 +81,266 @@ func TestIsValidIconName(t *testing.T) {
 		t.Errorf("Expected isValidIconName to return true for 'Joy'")
 	}
 }
+
+func TestProcessCatFact(t *testing.T) {
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		return "", errors.New("down")
+	}
+	instance := &tacomoev1beta1.CatFact{}
+	result, err := ProcessCatFact(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.FactGenerated || result.ProviderErr == nil || !result.IconGenerated {
+		t.Errorf("Unexpected result %+v", result)
+	}
+	if instance.Status.Fact != PlaceholderFact || instance.Status.Source != "placeholder" {
+		t.Errorf("Expected placeholder fact, got %+v", instance.Status)
+	}
+
+	instance = &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{Fact: "Cats are cool!", IconName: "Invalid"},
+	}
+	result, err = ProcessCatFact(context.Background(), instance)
+	if !errors.Is(err, ErrInvalidIconName) {
+		t.Errorf("Expected ErrInvalidIconName, got %v", err)
+	}
+	if result.FactGenerated || result.IconGenerated {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestProcessCatFactSpec(t *testing.T) {
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		t.Fatal("Expected spec.fact to be used instead of generating a fact")
+		return "", nil
+	}
+	instance := &tacomoev1beta1.CatFact{
+		ObjectMeta: metav1.ObjectMeta{Generation: 2},
+		Spec:       tacomoev1beta1.CatFactSpec{Fact: "Cats sleep a lot", IconName: "Joy"},
+		Status:     tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", Source: "catfact.ninja"},
+	}
+	result, err := ProcessCatFact(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := tacomoev1beta1.CatFactStatus{Fact: "Cats sleep a lot", IconName: "Joy", Category: CategoryBehavior, ObservedGeneration: 2}
+	if !reflect.DeepEqual(instance.Status, want) || result.FactGenerated || result.IconGenerated {
+		t.Errorf("Expected status %+v, got %+v with result %+v", want, instance.Status, result)
+	}
+}
+
+func TestProcessClusterCatFact(t *testing.T) {
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		return "Cats are everywhere", nil
+	}
+	instance := &tacomoev1beta1.ClusterCatFact{
+		ObjectMeta: metav1.ObjectMeta{Name: "everywhere", Generation: 3},
+		Spec: tacomoev1beta1.ClusterCatFactSpec{
+			CatFactSpec: tacomoev1beta1.CatFactSpec{IconName: "Hearts"},
+		},
+	}
+	result, err := ProcessCatFact(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.FactGenerated || instance.Status.Fact != "Cats are everywhere" {
+		t.Errorf("Expected a generated fact, got %+v", instance.Status)
+	}
+	if instance.Status.IconName != "Hearts" || instance.Status.ObservedGeneration != 3 {
+		t.Errorf("Expected iconName Hearts at generation 3, got %+v", instance.Status)
+	}
+}
+
+func TestProcessCatFactRefresh(t *testing.T) {
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		return "Cats sleep a lot", nil
+	}
+	lastRefresh := metav1.NewTime(time.Now().Add(-2 * time.Hour))
+	instance := &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{
+			IconName:      "Joy",
+			RefreshPolicy: &tacomoev1beta1.RefreshPolicy{Interval: metav1.Duration{Duration: time.Hour}},
+		},
+		Status: tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", Source: "catfact.ninja", LastRefreshTime: &lastRefresh},
+	}
+	result, err := ProcessCatFact(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !result.FactGenerated || instance.Status.Fact != "Cats sleep a lot" {
+		t.Errorf("Expected the fact to be refreshed, got %+v", instance.Status)
+	}
+	if result.RefreshAfter <= 59*time.Minute || result.RefreshAfter > time.Hour {
+		t.Errorf("Expected the next refresh in an hour, got %s", result.RefreshAfter)
+	}
+
+	result, _ = ProcessCatFact(context.Background(), instance)
+	if result.FactGenerated {
+		t.Errorf("Expected the fact not to be refreshed again before the interval")
+	}
+}
+
+func TestProcessCatFactSourceRef(t *testing.T) {
+	SetFactProviders(&CatFactNinjaProvider{URL: "https://a.example.com"}, &CatFactNinjaProvider{URL: "https://b.example.com", DisplayName: "b"})
+	defer SetFactProviders(&CatFactNinjaProvider{URL: DefaultFactURL})
+	var requested string
+	GetFactFromURL = func(_ context.Context, url string) (string, error) {
+		requested = url
+		return "Cats are cool!", nil
+	}
+
+	instance := &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{SourceRef: &tacomoev1beta1.FactSourceReference{Name: "b"}},
+	}
+	if _, err := ProcessCatFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requested != "https://b.example.com" || instance.Status.Source != "b" {
+		t.Errorf("Expected the fact to come from source b, got %s from %s", instance.Status.Source, requested)
+	}
+
+	instance = &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{SourceRef: &tacomoev1beta1.FactSourceReference{Name: "missing"}},
+	}
+	if _, err := ProcessCatFact(context.Background(), instance); !errors.Is(err, ErrUnknownFactSource) {
+		t.Errorf("Expected ErrUnknownFactSource, got %v", err)
+	}
+}
+
+func TestProcessCatFactCategory(t *testing.T) {
+	SetFactProviders(&CatFactNinjaProvider{URL: "https://example.com/fact", CategoryURL: "https://example.com/facts/{category}"})
+	defer SetFactProviders(&CatFactNinjaProvider{URL: DefaultFactURL})
+	var requested string
+	GetFactFromURL = func(_ context.Context, url string) (string, error) {
+		requested = url
+		return "Cats are cool!", nil
+	}
+
+	instance := &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{Category: CategoryHistory},
+	}
+	if _, err := ProcessCatFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requested != "https://example.com/facts/history" || instance.Status.Category != CategoryHistory {
+		t.Errorf("Expected a history fact from the category URL, got %s from %s", instance.Status.Category, requested)
+	}
+
+	// Facts from sources without a category URL are classified
+	SetFactProviders(&CatFactNinjaProvider{URL: "https://example.com/fact"})
+	GetFactFromURL = func(_ context.Context, url string) (string, error) {
+		requested = url
+		return "Cats purr when they groom each other", nil
+	}
+	instance = &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{Category: CategoryHistory},
+	}
+	if _, err := ProcessCatFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if requested != "https://example.com/fact" || instance.Status.Category != CategoryBehavior {
+		t.Errorf("Expected a classified behavior fact, got %s from %s", instance.Status.Category, requested)
+	}
+}
+
+func TestProcessCatFactLocked(t *testing.T) {
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		t.Fatal("Expected the locked fact to be kept")
+		return "", nil
+	}
+	SetAllowedIconNames([]string{"Evil"})
+	defer SetAllowedIconNames(nil)
+
+	lastRefresh := metav1.NewTime(time.Now().Add(-48 * time.Hour))
+	instance := &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{
+			Locked:        true,
+			Locale:        "es",
+			RefreshPolicy: &tacomoev1beta1.RefreshPolicy{Interval: metav1.Duration{Duration: time.Hour}},
+		},
+		Status: tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", IconName: "Joy", Source: "catfact.ninja", LastRefreshTime: &lastRefresh},
+	}
+	result, err := ProcessCatFact(context.Background(), instance)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if instance.Status.Fact != "Cats are cool!" || instance.Status.IconName != "Joy" {
+		t.Errorf("Expected the fact and icon to be kept, got %+v", instance.Status)
+	}
+	if result.FactGenerated || result.IconGenerated || result.RefreshAfter != 0 || result.TranslationErr != nil {
+		t.Errorf("Expected nothing to be generated or scheduled, got %+v", result)
+	}
+
+	// A fact set in the update that locked the CatFact is used
+	instance.Spec.Fact = "Cats sleep a lot"
+	if _, err := ProcessCatFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if instance.Status.Fact != "Cats sleep a lot" {
+		t.Errorf("Expected spec.fact to be used, got %+v", instance.Status)
+	}
+}
+
+func TestAllowedIconNames(t *testing.T) {
+	SetAllowedIconNames([]string{"Joy"})
+	defer SetAllowedIconNames(nil)
+
+	for i := 0; i < 10; i++ {
+		if name := RandomIconName(); name != "Joy" {
+			t.Fatalf("Expected only Joy to be generated, got %s", name)
+		}
+	}
+	if isValidIconName("Evil") {
+		t.Errorf("Expected Evil to be rejected when only Joy is allowed")
+	}
+
+	SetAllowedIconNames(nil)
+	if !isValidIconName("Evil") {
+		t.Errorf("Expected Evil to be allowed by default")
+	}
+}
+
+func TestProcessCatFactTracing(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := tracing.NewTracerProvider(exporter, 1)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	defer otel.SetTracerProvider(noop.NewTracerProvider())
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+		w.Write([]byte(`{"fact":"Cats are cool!","length":14}`))
+	}))
+	defer server.Close()
+	GetFactFromURL = getFactFromURL
+	SetFactProviders(&CatFactNinjaProvider{URL: server.URL})
+	defer SetFactProviders(&CatFactNinjaProvider{URL: DefaultFactURL})
+
+	ctx, span := tracing.Start(context.Background(), "test")
+	if _, err := ProcessCatFact(ctx, &tacomoev1beta1.CatFact{}); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	span.End()
+	if err := provider.ForceFlush(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	traceID := span.SpanContext().TraceID()
+	if !strings.Contains(traceparent, traceID.String()) {
+		t.Errorf("Expected fact API request to carry trace %s, got traceparent %q", traceID, traceparent)
+	}
+	names := map[string]bool{}
+	for _, s := range exporter.GetSpans() {
+		if s.SpanContext.TraceID() != traceID {
+			t.Errorf("Span %s is not part of trace %s", s.Name, traceID)
+		}
+		names[s.Name] = true
+	}
+	for _, name := range []string{"ProcessCatFact", "FactProvider.Fact", "HTTP GET"} {
+		if !names[name] {
+			t.Errorf("Expected a %s span, got %v", name, names)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sync/atomic"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
+)
+
+// Moderator decides whether a generated fact may be shown
+type Moderator interface {
+	// Name shown in logs, metrics, status, and Events
+	Name() string
+
+	// Moderate returns false and the reason if fact must not be shown
+	Moderate(ctx context.Context, fact string) (allowed bool, reason string, err error)
+}
+
+// Facts fetched for a CatFact before giving up and using the placeholder fact
+// because every one was rejected
+const MaxModerationAttempts = 3
+
+// BlocklistModerator rejects facts containing blocked words or matching
+// blocked patterns
+type BlocklistModerator struct {
+	words    []*regexp.Regexp
+	patterns []*regexp.Regexp
+}
+
+var _ Moderator = &BlocklistModerator{}
+
+// Return a BlocklistModerator rejecting facts containing any of words, case
+// insensitively and as whole words, or matching any of the regular
+// expressions in patterns
+func NewBlocklistModerator(words, patterns []string) (*BlocklistModerator, error) {
+	m := &BlocklistModerator{}
+	for _, word := range words {
+		m.words = append(m.words, regexp.MustCompile(`(?i)\b`+regexp.QuoteMeta(word)+`\b`))
+	}
+	for _, pattern := range patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid blocklist pattern %q: %w", pattern, err)
+		}
+		m.patterns = append(m.patterns, re)
+	}
+	return m, nil
+}
+
+func (m *BlocklistModerator) Name() string {
+	return "blocklist"
+}
+
+func (m *BlocklistModerator) Moderate(_ context.Context, fact string) (bool, string, error) {
+	for _, word := range m.words {
+		if word.MatchString(fact) {
+			// The word itself isn't repeated in status and Events
+			return false, "contains a blocked word", nil
+		}
+	}
+	for _, pattern := range m.patterns {
+		if pattern.MatchString(fact) {
+			return false, "matches blocked pattern " + pattern.String(), nil
+		}
+	}
+	return true, "", nil
+}
+
+// WebhookModerator asks an HTTP service whether facts may be shown. It POSTs
+// {"fact": "..."} and expects {"allowed": true|false, "reason": "..."}.
+type WebhookModerator struct {
+	// URL of the moderation endpoint
+	URL string
+
+	// Allow facts when the webhook can't be reached or fails, instead of
+	// rejecting them
+	IgnoreFailures bool
+}
+
+var _ Moderator = &WebhookModerator{}
+
+type moderationRequest struct {
+	Fact string `json:"fact"`
+}
+
+type moderationResponse struct {
+	Allowed bool   `json:"allowed"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+func (m *WebhookModerator) Name() string {
+	return "webhook"
+}
+
+func (m *WebhookModerator) Moderate(ctx context.Context, fact string) (bool, string, error) {
+	response, err := m.post(ctx, fact)
+	if err != nil && m.IgnoreFailures {
+		return true, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return response.Allowed, response.Reason, nil
+}
+
+func (m *WebhookModerator) post(ctx context.Context, fact string) (*moderationResponse, error) {
+	body, err := json.Marshal(moderationRequest{Fact: fact})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := factClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status from %s: %s", m.URL, res.Status)
+	}
+	response := &moderationResponse{}
+	if err := json.NewDecoder(res.Body).Decode(response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// Configured moderators. Swapped atomically so they can be reloaded while
+// CatFacts are being reconciled.
+var moderators atomic.Pointer[[]Moderator]
+
+// Return the configured moderators
+func Moderators() []Moderator {
+	if m := moderators.Load(); m != nil {
+		return *m
+	}
+	return nil
+}
+
+// Replace the configured moderators. Every moderator must allow a generated
+// fact for it to be shown. No moderators disables moderation.
+func SetModerators(m ...Moderator) {
+	moderators.Store(&m)
+}
+
+// Ask every moderator whether fact may be shown. Returns the name of the
+// moderator that rejected it and why, or empty strings if it's allowed. A
+// moderator that fails rejects the fact.
+func Moderate(ctx context.Context, fact string) (rejectedBy, reason string) {
+	ctx, span := tracing.Start(ctx, "Moderator.Moderate")
+	defer span.End()
+
+	for _, moderator := range Moderators() {
+		allowed, reason, err := moderator.Moderate(ctx, fact)
+		if err != nil {
+			tracing.RecordError(span, err)
+			allowed, reason = false, "moderation failed: "+err.Error()
+		}
+		if !allowed {
+			span.SetAttributes(attribute.String("catfact.rejected_by", moderator.Name()))
+			metrics.FactsRejected.WithLabelValues(moderator.Name()).Inc()
+			return moderator.Name(), reason
+		}
+	}
+	return "", ""
+}
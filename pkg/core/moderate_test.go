@@ -0,0 +1,126 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func TestBlocklistModerator(t *testing.T) {
+	moderator, err := NewBlocklistModerator([]string{"dog"}, []string{`\d{3}-\d{4}`})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	tests := map[string]bool{
+		"Cats are cool!":                 true,
+		"Cats are better than DOGs":      true,
+		"Cats are better than a Dog":     false,
+		"Call 555-1234 for more cats":    false,
+		"Cats have 230 bones, dogs 320.": true,
+	}
+	for fact, want := range tests {
+		allowed, reason, err := moderator.Moderate(context.Background(), fact)
+		if err != nil || allowed != want {
+			t.Errorf("%q: expected allowed %t, got %t (%s, %v)", fact, want, allowed, reason, err)
+		}
+	}
+
+	if _, err := NewBlocklistModerator(nil, []string{"(unclosed"}); err == nil {
+		t.Error("Expected an error for an invalid pattern")
+	}
+}
+
+func TestWebhookModerator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req moderationRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		switch req.Fact {
+		case "Cats are cool!":
+			w.Write([]byte(`{"allowed":true}`))
+		case "broken":
+			w.WriteHeader(http.StatusInternalServerError)
+		default:
+			w.Write([]byte(`{"allowed":false,"reason":"off topic"}`))
+		}
+	}))
+	defer server.Close()
+	moderator := &WebhookModerator{URL: server.URL}
+
+	if allowed, _, err := moderator.Moderate(context.Background(), "Cats are cool!"); !allowed || err != nil {
+		t.Errorf("Expected the fact to be allowed, got %t (%v)", allowed, err)
+	}
+	if allowed, reason, _ := moderator.Moderate(context.Background(), "Dogs are cool!"); allowed || reason != "off topic" {
+		t.Errorf("Expected the fact to be rejected as off topic, got %t (%s)", allowed, reason)
+	}
+	if allowed, _, err := moderator.Moderate(context.Background(), "broken"); allowed || err == nil {
+		t.Errorf("Expected the webhook failure to be returned, got %t (%v)", allowed, err)
+	}
+	moderator.IgnoreFailures = true
+	if allowed, _, err := moderator.Moderate(context.Background(), "broken"); !allowed || err != nil {
+		t.Errorf("Expected the webhook failure to be ignored, got %t (%v)", allowed, err)
+	}
+}
+
+func TestWebhookModeratorTimeout(t *testing.T) {
+	hung := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-hung
+	}))
+	defer server.Close()
+	defer close(hung)
+	defer func(timeout time.Duration) { factClient.Timeout = timeout }(factClient.Timeout)
+	factClient.Timeout = 50 * time.Millisecond
+
+	start := time.Now()
+	if _, _, err := (&WebhookModerator{URL: server.URL}).Moderate(context.Background(), "Cats are cool!"); err == nil {
+		t.Error("Expected a hung webhook to time out")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the request to time out quickly, took %s", elapsed)
+	}
+}
+
+func TestGenerateFactModeration(t *testing.T) {
+	moderator, _ := NewBlocklistModerator([]string{"dogs"}, nil)
+	SetModerators(moderator)
+	defer SetModerators()
+
+	facts := []string{"Dogs are cool!", "Cats are cool!"}
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		fact := facts[0]
+		facts = facts[1:]
+		return fact, nil
+	}
+	instance := &tacomoev1beta1.CatFact{}
+	if err := GenerateFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	want := tacomoev1beta1.ModerationStatus{
+		Decision:  tacomoev1beta1.ModerationAllowed,
+		Rejected:  1,
+		Moderator: "blocklist",
+		Reason:    "contains a blocked word",
+	}
+	if instance.Status.Fact != "Cats are cool!" || instance.Status.Moderation == nil || *instance.Status.Moderation != want {
+		t.Errorf("Expected the second fact to be allowed, got %+v", instance.Status)
+	}
+
+	// Every fact is rejected
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		return "Dogs are cool!", nil
+	}
+	if err := GenerateFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	moderation := instance.Status.Moderation
+	if instance.Status.Fact != PlaceholderFact || instance.Status.Source != "placeholder" ||
+		moderation.Decision != tacomoev1beta1.ModerationReplaced || moderation.Rejected != MaxModerationAttempts {
+		t.Errorf("Expected the placeholder fact after %d rejections, got %+v %+v", MaxModerationAttempts, instance.Status, moderation)
+	}
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strings"
+	"sync/atomic"
+)
+
+// FactProvider is a source of facts about cats.
+type FactProvider interface {
+	// Name of the provider. Used in logs and health reports.
+	Name() string
+
+	// Fact returns a random fact. Requests made by the provider should use
+	// ctx so they're cancelled and traced with the reconcile.
+	Fact(ctx context.Context) (string, error)
+}
+
+// CategoryFactProvider is a FactProvider that may serve facts in a category.
+type CategoryFactProvider interface {
+	FactProvider
+
+	// FactInCategory returns a random fact in category, one of Categories().
+	// Returns ErrCategoryUnsupported if the provider can't serve facts by
+	// category.
+	FactInCategory(ctx context.Context, category string) (string, error)
+}
+
+// Returned by CategoryFactProvider.FactInCategory when the provider can't
+// serve facts by category
+var ErrCategoryUnsupported = errors.New("fact source doesn't serve facts by category")
+
+// Placeholder in CatFactNinjaProvider.CategoryURL replaced by the category
+const CategoryPlaceholder = "{category}"
+
+// Default URL of the catfact.ninja API
+const DefaultFactURL = "https://catfact.ninja/fact"
+
+// CatFactNinjaProvider gets facts from the https://catfact.ninja API.
+type CatFactNinjaProvider struct {
+	URL string
+
+	// Optional name used instead of "catfact.ninja", e.g. for a mirror
+	DisplayName string
+
+	// Optional URL serving facts in a category, with CategoryPlaceholder
+	// replaced by the category. catfact.ninja itself doesn't have one, but
+	// compatible APIs may.
+	CategoryURL string
+}
+
+var _ CategoryFactProvider = &CatFactNinjaProvider{}
+
+func (p *CatFactNinjaProvider) Name() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return "catfact.ninja"
+}
+
+func (p *CatFactNinjaProvider) Fact(ctx context.Context) (string, error) {
+	return GetFactFromURL(ctx, p.URL)
+}
+
+func (p *CatFactNinjaProvider) FactInCategory(ctx context.Context, category string) (string, error) {
+	if p.CategoryURL == "" {
+		return "", ErrCategoryUnsupported
+	}
+	return GetFactFromURL(ctx, strings.ReplaceAll(p.CategoryURL, CategoryPlaceholder, url.PathEscape(category)))
+}
+
+// Configured providers. Swapped atomically so they can be reloaded while
+// CatFacts are being reconciled.
+var factProviders atomic.Pointer[[]FactProvider]
+
+func init() {
+	SetFactProviders(&CatFactNinjaProvider{URL: DefaultFactURL})
+}
+
+// Return the configured fact providers
+func FactProviders() []FactProvider {
+	return *factProviders.Load()
+}
+
+// Replace the configured fact providers. The first provider is used by
+// GenerateFact. Nothing is changed if no providers are given.
+func SetFactProviders(providers ...FactProvider) {
+	if len(providers) == 0 {
+		return
+	}
+	factProviders.Store(&providers)
+}
+
+// Return the provider used by GenerateFact when a CatFact doesn't have a fact
+func DefaultProvider() FactProvider {
+	return FactProviders()[0]
+}
+
+// Return the configured fact provider with the given name
+func FactProviderByName(name string) (FactProvider, bool) {
+	for _, provider := range FactProviders() {
+		if provider.Name() == name {
+			return provider, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,168 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Aggregate a CatFact's votes into status.rating and status.votes. Votes on
+// other CatFacts are ignored. Each user counts once: if a user has several
+// votes, e.g. one created directly under another name, the one named by
+// VoteName wins, or else the one with the first name.
+func RateCatFact(catFact *tacomoev1beta1.CatFact, votes []tacomoev1beta1.CatFactVote) {
+	byUser := map[string]tacomoev1beta1.CatFactVote{}
+	for _, vote := range votes {
+		if vote.Namespace != catFact.Namespace || vote.Spec.CatFactName != catFact.Name {
+			continue
+		}
+		if counted, ok := byUser[vote.Spec.User]; ok && !preferVote(vote, counted) {
+			continue
+		}
+		byUser[vote.Spec.User] = vote
+	}
+	var rating int32
+	for _, vote := range byUser {
+		rating += vote.Spec.Value
+	}
+	catFact.Status.Rating, catFact.Status.Votes = rating, int32(len(byUser))
+}
+
+// Return true if vote should be counted instead of another vote by the same
+// user
+func preferVote(vote, other tacomoev1beta1.CatFactVote) bool {
+	canonical := tacomoev1beta1.VoteName(vote.Spec.CatFactName, vote.Spec.User)
+	if (vote.Name == canonical) != (other.Name == canonical) {
+		return vote.Name == canonical
+	}
+	return vote.Name < other.Name
+}
+
+// How long FactRatings reuses the ratings it last read, so generating facts
+// doesn't list every CatFact each time
+const RatingsCacheTTL = time.Minute
+
+// Reads CatFacts for their ratings. Swapped atomically like the fact
+// providers; nil until SetRatingsReader is called.
+var ratingsReader atomic.Pointer[client.Reader]
+
+// Ratings FactRatings last read, and when to read them again
+type cachedRatings struct {
+	totals  map[string]int32
+	expires time.Time
+}
+
+var ratingsCache atomic.Pointer[cachedRatings]
+
+// Set the reader FactRatings lists CatFacts with. It must see every CatFact,
+// so when sharding it can't be the manager's cache.
+func SetRatingsReader(reader client.Reader) {
+	ratingsReader.Store(&reader)
+	ratingsCache.Store(nil)
+}
+
+// Return the total status.rating of each rated fact across all CatFacts, so
+// a fact rated on several CatFacts counts every rating. Translated facts are
+// rated in English. Ratings are read at most once per RatingsCacheTTL, and
+// the returned map must not be changed.
+func FactRatings(ctx context.Context) (map[string]int32, error) {
+	reader := ratingsReader.Load()
+	if reader == nil {
+		return map[string]int32{}, nil
+	}
+	now := time.Now()
+	if cached := ratingsCache.Load(); cached != nil && now.Before(cached.expires) {
+		return cached.totals, nil
+	}
+	var catFacts tacomoev1beta1.CatFactList
+	if err := (*reader).List(ctx, &catFacts); err != nil {
+		return nil, err
+	}
+	totals := map[string]int32{}
+	for _, catFact := range catFacts.Items {
+		if catFact.Status.Rating != 0 {
+			totals[originalFact(&catFact.Status)] += catFact.Status.Rating
+		}
+	}
+	ratingsCache.Store(&cachedRatings{totals: totals, expires: now.Add(RatingsCacheTTL)})
+	return totals, nil
+}
+
+// Returned by LibraryProvider.Fact when the library has no facts
+var ErrEmptyLibrary = errors.New("fact library is empty")
+
+// LibraryProvider picks facts from a curated list, preferring highly rated
+// facts. A fact with rating r is picked with weight r+1, or 1/(1-r) if it's
+// downvoted, so unrated facts are still picked. If ratings can't be read,
+// every fact is picked with the same weight.
+type LibraryProvider struct {
+	// Name of the library
+	DisplayName string
+
+	// The curated facts
+	Facts []string
+}
+
+var _ FactProvider = &LibraryProvider{}
+
+func (p *LibraryProvider) Name() string {
+	if p.DisplayName != "" {
+		return p.DisplayName
+	}
+	return "library"
+}
+
+func (p *LibraryProvider) Fact(ctx context.Context) (string, error) {
+	if len(p.Facts) == 0 {
+		return "", ErrEmptyLibrary
+	}
+	factRatings, err := FactRatings(ctx)
+	if err != nil {
+		log.FromContext(ctx).Error(err, "Unable to read fact ratings, picking facts unweighted", "library", p.Name())
+	}
+	weights := make([]float64, len(p.Facts))
+	var total float64
+	for i, fact := range p.Facts {
+		weights[i] = ratingWeight(factRatings[fact])
+		total += weights[i]
+	}
+	pick := rand.Float64() * total
+	for i, weight := range weights {
+		if pick < weight {
+			return p.Facts[i], nil
+		}
+		pick -= weight
+	}
+	return p.Facts[len(p.Facts)-1], nil
+}
+
+// Return the weight a fact with rating is picked with
+func ratingWeight(rating int32) float64 {
+	if rating >= 0 {
+		return float64(rating) + 1
+	}
+	return 1 / (1 - float64(rating))
+}
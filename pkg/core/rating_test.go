@@ -0,0 +1,141 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func newVote(namespace, catFactName, user string, value int32) tacomoev1beta1.CatFactVote {
+	return tacomoev1beta1.CatFactVote{
+		ObjectMeta: metav1.ObjectMeta{Name: tacomoev1beta1.VoteName(catFactName, user), Namespace: namespace},
+		Spec:       tacomoev1beta1.CatFactVoteSpec{CatFactName: catFactName, User: user, Value: value},
+	}
+}
+
+func TestRateCatFact(t *testing.T) {
+	catFact := &tacomoev1beta1.CatFact{ObjectMeta: metav1.ObjectMeta{Name: "cool", Namespace: "default"}}
+	votes := []tacomoev1beta1.CatFactVote{
+		newVote("default", "cool", "kitten", 1),
+		newVote("default", "cool", "tabby", 1),
+		newVote("default", "cool", "tom", -1),
+		newVote("default", "other", "kitten", 1),
+		newVote("other", "cool", "kitten", 1),
+	}
+	RateCatFact(catFact, votes)
+	if catFact.Status.Rating != 1 || catFact.Status.Votes != 3 {
+		t.Errorf("Expected rating 1 from 3 votes, got %d from %d", catFact.Status.Rating, catFact.Status.Votes)
+	}
+
+	// A user's duplicate votes count once, preferring the one named by VoteName
+	duplicate := newVote("default", "cool", "tom", 1)
+	duplicate.Name = "aaa-stuffed-ballot"
+	RateCatFact(catFact, append(votes, duplicate))
+	if catFact.Status.Rating != 1 || catFact.Status.Votes != 3 {
+		t.Errorf("Expected duplicate votes to be ignored, got rating %d from %d", catFact.Status.Rating, catFact.Status.Votes)
+	}
+	forged := newVote("default", "cool", "mallory", 1)
+	forged.Name = "vote-1"
+	other := forged
+	other.Name = "vote-2"
+	RateCatFact(catFact, []tacomoev1beta1.CatFactVote{other, forged})
+	if catFact.Status.Rating != 1 || catFact.Status.Votes != 1 {
+		t.Errorf("Expected one vote per user, got rating %d from %d", catFact.Status.Rating, catFact.Status.Votes)
+	}
+
+	RateCatFact(catFact, nil)
+	if catFact.Status.Rating != 0 || catFact.Status.Votes != 0 {
+		t.Errorf("Expected no rating without votes, got %+v", catFact.Status)
+	}
+}
+
+func TestVoteName(t *testing.T) {
+	if tacomoev1beta1.VoteName("cool", "kitten") != tacomoev1beta1.VoteName("cool", "kitten") {
+		t.Error("Expected a user's votes on a CatFact to have the same name")
+	}
+	if tacomoev1beta1.VoteName("cool", "kitten") == tacomoev1beta1.VoteName("cool", "tabby") {
+		t.Error("Expected different users' votes to have different names")
+	}
+}
+
+// Read ratings from a fake client holding catFacts until the test ends
+func useRatings(t *testing.T, catFacts ...client.Object) {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	tacomoev1beta1.AddToScheme(scheme)
+	SetRatingsReader(fake.NewClientBuilder().WithScheme(scheme).WithObjects(catFacts...).Build())
+	t.Cleanup(func() {
+		ratingsReader.Store(nil)
+		ratingsCache.Store(nil)
+	})
+}
+
+func rated(namespace, name, fact string, rating int32) *tacomoev1beta1.CatFact {
+	return &tacomoev1beta1.CatFact{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+		Status:     tacomoev1beta1.CatFactStatus{Fact: fact, Rating: rating},
+	}
+}
+
+func TestFactRatings(t *testing.T) {
+	// Translated facts are rated in English
+	translated := rated("other", "two", "Les chats sont cool !", 1)
+	translated.Status.OriginalFact = "Cats are cool!"
+	useRatings(t,
+		rated("default", "one", "Cats are cool!", 2),
+		rated("other", "one", "Cats are cool!", 3),
+		translated,
+		rated("other", "three", "Cats sleep a lot", 0),
+	)
+
+	ratings, err := FactRatings(context.Background())
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(ratings) != 1 || ratings["Cats are cool!"] != 6 {
+		t.Errorf("Expected ratings of the same fact to add up to 6, got %v", ratings)
+	}
+
+	// Ratings are cached, so new ratings aren't read until the cache expires
+	reader := *ratingsReader.Load()
+	if err := reader.(client.Client).Create(context.Background(), rated("default", "two", "Cats sleep a lot", 1)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if ratings, _ := FactRatings(context.Background()); len(ratings) != 1 {
+		t.Errorf("Expected the cached ratings, got %v", ratings)
+	}
+	ratingsCache.Store(&cachedRatings{totals: ratings, expires: time.Now()})
+	if ratings, _ := FactRatings(context.Background()); ratings["Cats sleep a lot"] != 1 {
+		t.Errorf("Expected ratings to be read again once the cache expired, got %v", ratings)
+	}
+}
+
+func TestLibraryProvider(t *testing.T) {
+	useRatings(t, rated("default", "favorite", "Cats sleep a lot", 9))
+	library := &LibraryProvider{Facts: []string{"Cats are cool!", "Cats sleep a lot"}}
+
+	picked := map[string]int{}
+	for i := 0; i < 1000; i++ {
+		fact, err := library.Fact(context.Background())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		picked[fact]++
+	}
+	// Picked with weights 1 and 10
+	if picked["Cats sleep a lot"] < 800 || picked["Cats are cool!"] == 0 {
+		t.Errorf("Expected the highly rated fact to be preferred, got %v", picked)
+	}
+
+	if _, err := (&LibraryProvider{}).Fact(context.Background()); !errors.Is(err, ErrEmptyLibrary) {
+		t.Errorf("Expected ErrEmptyLibrary, got %v", err)
+	}
+}
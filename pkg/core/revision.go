@@ -0,0 +1,141 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Revisions kept in status.history
+const MaxRevisions = 10
+
+// Returned by Rollback when the revision isn't the current revision or in
+// status.history. Retrying won't help until the annotation is changed.
+var ErrRevisionNotFound = errors.New("revision not found")
+
+// Returned by Rollback when the CatFact is locked
+var ErrLocked = errors.New("can't roll back a locked CatFact")
+
+// Record a new revision if the fact or icon in status changed from previous,
+// the status before the CatFact was processed. The replaced fact and icon
+// are added to status.history.
+func RecordRevision(instance tacomoev1beta1.CatFactObject, previous *tacomoev1beta1.CatFactStatus, now time.Time) {
+	status := instance.GetCatFactStatus()
+	if status.Fact == "" && status.IconName == "" {
+		return
+	}
+	if status.Fact == previous.Fact && status.IconName == previous.IconName {
+		if status.Revision == 0 {
+			// Resolved before revisions were recorded
+			status.Revision = nextRevision(status)
+		}
+		return
+	}
+	if previous.Fact != "" || previous.IconName != "" {
+		replaced := revisionOf(previous, now)
+		if replaced.Revision == 0 {
+			replaced.Revision = nextRevision(previous)
+		}
+		status.History = pushRevision(previous.History, *replaced)
+		status.Revision = max(replaced.Revision, status.Revision)
+	}
+	status.Revision = nextRevision(status)
+}
+
+// Roll the fact and icon in status back to a revision, given as the value of
+// RollbackAnnotation. The current fact and icon are added to status.history.
+// Returns the restored revision. Rolling back to the current revision
+// changes nothing.
+func Rollback(instance tacomoev1beta1.CatFactObject, value string, now time.Time) (*tacomoev1beta1.FactRevision, error) {
+	if instance.GetCatFactSpec().Locked {
+		return nil, ErrLocked
+	}
+	status := instance.GetCatFactStatus()
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision < 1 {
+		return nil, fmt.Errorf("%w: %q is not a revision number", ErrRevisionNotFound, value)
+	}
+	if revision == status.Revision {
+		return revisionOf(status, now), nil
+	}
+	i := slices.IndexFunc(status.History, func(r tacomoev1beta1.FactRevision) bool {
+		return r.Revision == revision
+	})
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %d is not in status.history", ErrRevisionNotFound, revision)
+	}
+
+	target := status.History[i]
+	history := slices.Delete(slices.Clone(status.History), i, i+1)
+	if status.Fact != "" || status.IconName != "" {
+		history = pushRevision(history, *revisionOf(status, now))
+	}
+	status.History = history
+	status.Revision = target.Revision
+	status.Fact, status.OriginalFact, status.Locale = target.Fact, target.OriginalFact, target.Locale
+	status.IconName = target.IconName
+	status.Source = target.Source
+	status.Category = target.Category
+	status.Moderation = nil
+	status.LastRefreshTime = nil
+	if target.Source != "" {
+		// Not refreshed until the refresh interval passes again
+		status.LastRefreshTime = &metav1.Time{Time: now}
+	}
+	return &target, nil
+}
+
+// Return the current revision of status, replaced at now
+func revisionOf(status *tacomoev1beta1.CatFactStatus, now time.Time) *tacomoev1beta1.FactRevision {
+	return &tacomoev1beta1.FactRevision{
+		Revision:     status.Revision,
+		Fact:         status.Fact,
+		OriginalFact: status.OriginalFact,
+		Locale:       status.Locale,
+		IconName:     status.IconName,
+		Source:       status.Source,
+		Category:     status.Category,
+		ReplacedTime: metav1.Time{Time: now},
+	}
+}
+
+// Return history with revision added first, keeping MaxRevisions revisions
+func pushRevision(history []tacomoev1beta1.FactRevision, revision tacomoev1beta1.FactRevision) []tacomoev1beta1.FactRevision {
+	history = append([]tacomoev1beta1.FactRevision{revision}, history...)
+	if len(history) > MaxRevisions {
+		history = history[:MaxRevisions]
+	}
+	return history
+}
+
+// Return a revision number higher than any in status. Revisions rolled back
+// from stay in history, so the current revision isn't always the highest.
+func nextRevision(status *tacomoev1beta1.CatFactStatus) int64 {
+	next := status.Revision
+	for _, revision := range status.History {
+		next = max(next, revision.Revision)
+	}
+	return next + 1
+}
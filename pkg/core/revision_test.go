@@ -0,0 +1,111 @@
+package core
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Return a CatFact showing fact and iconName, with the status it had before
+func newRevisedCatFact(previous tacomoev1beta1.CatFactStatus, fact, iconName string) (*tacomoev1beta1.CatFact, *tacomoev1beta1.CatFactStatus) {
+	instance := &tacomoev1beta1.CatFact{Status: *previous.DeepCopy()}
+	instance.Status.Fact, instance.Status.IconName = fact, iconName
+	return instance, &previous
+}
+
+func TestRecordRevision(t *testing.T) {
+	now := time.Now()
+
+	instance, previous := newRevisedCatFact(tacomoev1beta1.CatFactStatus{}, "Cats are cool!", "Joy")
+	RecordRevision(instance, previous, now)
+	if instance.Status.Revision != 1 || len(instance.Status.History) != 0 {
+		t.Fatalf("Expected the first revision without history, got %+v", instance.Status)
+	}
+
+	// Unchanged facts aren't new revisions
+	instance, previous = newRevisedCatFact(instance.Status, "Cats are cool!", "Joy")
+	RecordRevision(instance, previous, now)
+	if instance.Status.Revision != 1 {
+		t.Errorf("Expected revision 1, got %d", instance.Status.Revision)
+	}
+
+	instance, previous = newRevisedCatFact(instance.Status, "Cats sleep a lot", "Joy")
+	instance.Status.Source = "catfact.ninja"
+	RecordRevision(instance, previous, now)
+	history := instance.Status.History
+	if instance.Status.Revision != 2 || len(history) != 1 || history[0].Revision != 1 || history[0].Fact != "Cats are cool!" {
+		t.Errorf("Expected revision 2 with revision 1 in history, got %+v", instance.Status)
+	}
+
+	// History is bounded
+	for i := 0; i < MaxRevisions+5; i++ {
+		instance, previous = newRevisedCatFact(instance.Status, instance.Status.Fact, ValidIconNames()[i%2])
+		RecordRevision(instance, previous, now)
+	}
+	if len(instance.Status.History) != MaxRevisions || instance.Status.Revision != MaxRevisions+7 {
+		t.Errorf("Expected %d revisions in history, got %d (revision %d)", MaxRevisions, len(instance.Status.History), instance.Status.Revision)
+	}
+
+	// CatFacts resolved before revisions were recorded
+	instance, previous = newRevisedCatFact(tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!", IconName: "Joy"}, "Cats sleep a lot", "Joy")
+	RecordRevision(instance, previous, now)
+	if instance.Status.Revision != 2 || instance.Status.History[0].Revision != 1 {
+		t.Errorf("Expected revision 2 with revision 1 in history, got %+v", instance.Status)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	now := time.Now()
+	instance := &tacomoev1beta1.CatFact{Status: tacomoev1beta1.CatFactStatus{
+		Fact:     "Cats sleep a lot",
+		IconName: "Evil",
+		Source:   "catfact.ninja",
+		Revision: 3,
+		History: []tacomoev1beta1.FactRevision{
+			{Revision: 2, Fact: "Cats are cool!", IconName: "Joy", Source: "catfact.ninja", Category: CategoryGeneral},
+			{Revision: 1, Fact: "Cats have whiskers", IconName: "Joy"},
+		},
+	}}
+
+	revision, err := Rollback(instance, "2", now)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	status := instance.Status
+	if revision.Fact != "Cats are cool!" || status.Fact != "Cats are cool!" || status.IconName != "Joy" || status.Revision != 2 {
+		t.Errorf("Expected revision 2 to be restored, got %+v", status)
+	}
+	if status.LastRefreshTime == nil || !status.LastRefreshTime.Time.Equal(now) {
+		t.Errorf("Expected the restored generated fact's refresh to restart, got %v", status.LastRefreshTime)
+	}
+	if len(status.History) != 2 || status.History[0].Revision != 3 || status.History[1].Revision != 1 {
+		t.Errorf("Expected revisions 3 and 1 in history, got %+v", status.History)
+	}
+
+	// Rolling back again changes nothing
+	before := *instance.Status.DeepCopy()
+	if _, err := Rollback(instance, "2", now.Add(time.Minute)); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if instance.Status.Revision != before.Revision || len(instance.Status.History) != len(before.History) {
+		t.Errorf("Expected rolling back to the current revision to change nothing, got %+v", instance.Status)
+	}
+
+	// Facts set in spec.fact aren't refreshed
+	if _, err := Rollback(instance, "1", now); err != nil || instance.Status.LastRefreshTime != nil {
+		t.Errorf("Expected revision 1 without a refresh time, got %+v (%v)", instance.Status, err)
+	}
+
+	for _, value := range []string{"5", "latest", "0"} {
+		if _, err := Rollback(instance, value, now); !errors.Is(err, ErrRevisionNotFound) {
+			t.Errorf("%s: expected ErrRevisionNotFound, got %v", value, err)
+		}
+	}
+
+	instance.Spec.Locked = true
+	if _, err := Rollback(instance, "3", now); !errors.Is(err, ErrLocked) {
+		t.Errorf("Expected ErrLocked, got %v", err)
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/metrics"
+	"github.com/ryanmillerc/cat-facts-operator/pkg/tracing"
+)
+
+// Locale facts are written in. Fact sources serve English facts.
+const SourceLocale = "en"
+
+// Translator translates facts from SourceLocale
+type Translator interface {
+	// Name shown in logs, metrics, and Events
+	Name() string
+
+	// Translate text into locale, a BCP 47 language tag. Returns
+	// ErrNoTranslation if the translator can't translate into locale.
+	Translate(ctx context.Context, text, locale string) (string, error)
+}
+
+// Returned when no translator has a translation of a fact
+var ErrNoTranslation = errors.New("no translation")
+
+// DictionaryTranslator translates facts it has a translation for, without
+// calling out of the cluster
+type DictionaryTranslator struct {
+	// Translations by locale, then by English fact. A locale with a region,
+	// e.g. "pt-BR", falls back to its language, "pt".
+	Translations map[string]map[string]string
+}
+
+var _ Translator = &DictionaryTranslator{}
+
+func (t *DictionaryTranslator) Name() string {
+	return "dictionary"
+}
+
+func (t *DictionaryTranslator) Translate(_ context.Context, text, locale string) (string, error) {
+	for _, l := range []string{locale, localeLanguage(locale)} {
+		if translated, ok := t.Translations[l][text]; ok {
+			return translated, nil
+		}
+	}
+	return "", fmt.Errorf("%w of %q into %s in the dictionary", ErrNoTranslation, text, locale)
+}
+
+// LibreTranslateTranslator translates facts with a LibreTranslate compatible
+// API
+type LibreTranslateTranslator struct {
+	// URL of the translate endpoint, e.g. http://libretranslate:5000/translate
+	URL string
+
+	// Optional API key sent with every request
+	APIKey string
+}
+
+var _ Translator = &LibreTranslateTranslator{}
+
+type libreTranslateRequest struct {
+	Q      string `json:"q"`
+	Source string `json:"source"`
+	Target string `json:"target"`
+	Format string `json:"format"`
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type libreTranslateResponse struct {
+	TranslatedText string `json:"translatedText"`
+	Error          string `json:"error"`
+}
+
+func (t *LibreTranslateTranslator) Name() string {
+	return "libretranslate"
+}
+
+func (t *LibreTranslateTranslator) Translate(ctx context.Context, text, locale string) (string, error) {
+	body, err := json.Marshal(libreTranslateRequest{
+		Q:      text,
+		Source: SourceLocale,
+		// LibreTranslate only knows languages, not regions
+		Target: localeLanguage(locale),
+		Format: "text",
+		APIKey: t.APIKey,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.URL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := factClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+
+	var response libreTranslateResponse
+	if err := json.NewDecoder(res.Body).Decode(&response); err != nil && res.StatusCode == http.StatusOK {
+		return "", err
+	}
+	if res.StatusCode == http.StatusBadRequest {
+		// Returned for languages the server doesn't have
+		return "", fmt.Errorf("%w into %s from %s: %s", ErrNoTranslation, locale, t.URL, response.Error)
+	}
+	if res.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status from %s: %s %s", t.URL, res.Status, response.Error)
+	}
+	return response.TranslatedText, nil
+}
+
+// Configured translators. Swapped atomically so they can be reloaded while
+// CatFacts are being reconciled.
+var translators atomic.Pointer[[]Translator]
+
+// Return the configured translators
+func Translators() []Translator {
+	if t := translators.Load(); t != nil {
+		return *t
+	}
+	return nil
+}
+
+// Replace the configured translators. They're tried in order until one has a
+// translation. No translators disables translation.
+func SetTranslators(t ...Translator) {
+	translators.Store(&t)
+}
+
+// Translate text into locale with the first translator that has a
+// translation. Returns the translation and the translator's name.
+func Translate(ctx context.Context, text, locale string) (string, string, error) {
+	ctx, span := tracing.Start(ctx, "Translator.Translate", trace.WithAttributes(
+		attribute.String("catfact.locale", locale),
+	))
+	defer span.End()
+
+	for _, translator := range Translators() {
+		translated, err := translator.Translate(ctx, text, locale)
+		if errors.Is(err, ErrNoTranslation) {
+			continue
+		}
+		result := "translated"
+		if err != nil {
+			result = "error"
+		}
+		metrics.Translations.WithLabelValues(translator.Name(), result).Inc()
+		tracing.RecordError(span, err)
+		return translated, translator.Name(), err
+	}
+	metrics.Translations.WithLabelValues("none", "missing").Inc()
+	err := fmt.Errorf("%w into %s", ErrNoTranslation, locale)
+	tracing.RecordError(span, err)
+	return "", "", err
+}
+
+// Delay before retrying a failed translation, doubling with every failed
+// attempt up to the max
+const (
+	TranslationRetryBaseDelay = time.Minute
+	TranslationRetryMaxDelay  = time.Hour
+)
+
+// Translate a CatFact's fact into spec.locale. The untranslated fact is kept
+// in status.originalFact. If it can't be translated, the untranslated fact is
+// shown, the failure is recorded in status.translationFailure, and the error
+// is returned. Until the failure is due to be retried, see
+// TranslationRetryAfter, nothing is done.
+func TranslateFact(ctx context.Context, instance tacomoev1beta1.CatFactObject, now time.Time) error {
+	locale := instance.GetCatFactSpec().Locale
+	status := instance.GetCatFactStatus()
+	original := originalFact(status)
+	if locale == "" || localeLanguage(locale) == SourceLocale {
+		status.Fact, status.OriginalFact, status.Locale = original, "", ""
+		status.TranslationFailure = nil
+		return nil
+	}
+	if status.Locale == locale {
+		// Already translated
+		return nil
+	}
+	if TranslationRetryAfter(instance, now) > 0 {
+		status.Fact, status.OriginalFact, status.Locale = original, "", ""
+		return nil
+	}
+
+	translated, _, err := Translate(ctx, original, locale)
+	if err != nil {
+		status.Fact, status.OriginalFact, status.Locale = original, "", ""
+		failure := &tacomoev1beta1.TranslationFailure{Locale: locale, Fact: original, Attempts: 1}
+		if previous := status.TranslationFailure; previous != nil && previous.Locale == locale && previous.Fact == original {
+			failure.Attempts = previous.Attempts + 1
+		}
+		failure.LastAttemptTime = metav1.Time{Time: now}
+		failure.Reason = err.Error()
+		status.TranslationFailure = failure
+		return err
+	}
+	status.Fact, status.OriginalFact, status.Locale = translated, original, locale
+	status.TranslationFailure = nil
+	return nil
+}
+
+// Return the time until translating a CatFact's fact should be retried, or
+// zero if it isn't waiting to be retried
+func TranslationRetryAfter(instance tacomoev1beta1.CatFactObject, now time.Time) time.Duration {
+	status := instance.GetCatFactStatus()
+	failure := status.TranslationFailure
+	if failure == nil || failure.Locale != instance.GetCatFactSpec().Locale || failure.Fact != originalFact(status) {
+		return 0
+	}
+	delay := TranslationRetryMaxDelay
+	if failure.Attempts < 7 {
+		delay = min(TranslationRetryBaseDelay<<max(failure.Attempts-1, 0), TranslationRetryMaxDelay)
+	}
+	return max(failure.LastAttemptTime.Add(delay).Sub(now), 0)
+}
+
+// Return a CatFact's fact before it was translated
+func originalFact(status *tacomoev1beta1.CatFactStatus) string {
+	if status.OriginalFact != "" {
+		return status.OriginalFact
+	}
+	return status.Fact
+}
+
+// Return the language of a BCP 47 language tag, e.g. "pt" for "pt-BR"
+func localeLanguage(locale string) string {
+	language, _, _ := strings.Cut(locale, "-")
+	return strings.ToLower(language)
+}
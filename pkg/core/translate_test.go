@@ -0,0 +1,218 @@
+package core
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func TestDictionaryTranslator(t *testing.T) {
+	translator := &DictionaryTranslator{Translations: map[string]map[string]string{
+		"pt":    {"Cats are cool!": "Gatos são legais!"},
+		"pt-BR": {"Cats sleep a lot": "Gatos dormem muito"},
+	}}
+
+	tests := []struct {
+		text, locale, want string
+	}{
+		{"Cats are cool!", "pt", "Gatos são legais!"},
+		{"Cats are cool!", "pt-BR", "Gatos são legais!"},
+		{"Cats sleep a lot", "pt-BR", "Gatos dormem muito"},
+	}
+	for _, test := range tests {
+		got, err := translator.Translate(context.Background(), test.text, test.locale)
+		if err != nil || got != test.want {
+			t.Errorf("%s into %s: expected %q, got %q (%v)", test.text, test.locale, test.want, got, err)
+		}
+	}
+
+	if _, err := translator.Translate(context.Background(), "Cats sleep a lot", "pt"); !errors.Is(err, ErrNoTranslation) {
+		t.Errorf("Expected ErrNoTranslation, got %v", err)
+	}
+}
+
+func TestLibreTranslateTranslator(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req libreTranslateRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Unable to decode request: %v", err)
+		}
+		if req.Source != SourceLocale || req.Format != "text" || req.APIKey != "secret" {
+			t.Errorf("Unexpected request %+v", req)
+		}
+		switch req.Target {
+		case "es":
+			w.Write([]byte(`{"translatedText":"¡Los gatos son geniales!"}`))
+		case "xx":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error":"xx is not supported"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte(`{"error":"broken"}`))
+		}
+	}))
+	defer server.Close()
+	translator := &LibreTranslateTranslator{URL: server.URL, APIKey: "secret"}
+
+	got, err := translator.Translate(context.Background(), "Cats are cool!", "es-MX")
+	if err != nil || got != "¡Los gatos son geniales!" {
+		t.Errorf("Expected a Spanish fact, got %q (%v)", got, err)
+	}
+	if _, err := translator.Translate(context.Background(), "Cats are cool!", "xx"); !errors.Is(err, ErrNoTranslation) {
+		t.Errorf("Expected ErrNoTranslation for an unsupported language, got %v", err)
+	}
+	if _, err := translator.Translate(context.Background(), "Cats are cool!", "de"); err == nil || errors.Is(err, ErrNoTranslation) {
+		t.Errorf("Expected a server error, got %v", err)
+	}
+}
+
+func TestProcessCatFactLocale(t *testing.T) {
+	SetTranslators(
+		&DictionaryTranslator{Translations: map[string]map[string]string{
+			"es": {"Cats sleep a lot": "Los gatos duermen mucho"},
+		}},
+		&DictionaryTranslator{Translations: map[string]map[string]string{
+			"es": {"Cats are cool!": "¡Los gatos son geniales!"},
+			"fr": {"Cats are cool!": "Les chats sont cool !"},
+		}},
+	)
+	defer SetTranslators()
+
+	instance := &tacomoev1beta1.CatFact{
+		ObjectMeta: metav1.ObjectMeta{Generation: 1},
+		Spec:       tacomoev1beta1.CatFactSpec{Fact: "Cats are cool!", IconName: "Joy", Locale: "es"},
+	}
+	result, err := ProcessCatFact(context.Background(), instance)
+	if err != nil || result.TranslationErr != nil {
+		t.Fatalf("Unexpected errors: %v, %v", err, result.TranslationErr)
+	}
+	want := tacomoev1beta1.CatFactStatus{
+		Fact:               "¡Los gatos son geniales!",
+		OriginalFact:       "Cats are cool!",
+		Locale:             "es",
+		IconName:           "Joy",
+		Category:           CategoryGeneral,
+		ObservedGeneration: 1,
+	}
+	if !reflect.DeepEqual(instance.Status, want) {
+		t.Errorf("Expected status %+v, got %+v", want, instance.Status)
+	}
+
+	// Changing the locale translates the original fact
+	instance.Spec.Locale = "fr"
+	if _, err := ProcessCatFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if instance.Status.Fact != "Les chats sont cool !" || instance.Status.OriginalFact != "Cats are cool!" {
+		t.Errorf("Expected a French fact, got %+v", instance.Status)
+	}
+
+	// Facts without a translation are shown in English
+	instance.Spec.Locale = "de"
+	result, _ = ProcessCatFact(context.Background(), instance)
+	if !errors.Is(result.TranslationErr, ErrNoTranslation) {
+		t.Errorf("Expected ErrNoTranslation, got %v", result.TranslationErr)
+	}
+	if instance.Status.Fact != "Cats are cool!" || instance.Status.OriginalFact != "" || instance.Status.Locale != "" {
+		t.Errorf("Expected the untranslated fact, got %+v", instance.Status)
+	}
+	if failure := instance.Status.TranslationFailure; failure == nil || failure.Locale != "de" || failure.Attempts != 1 {
+		t.Errorf("Expected the failure to be recorded, got %+v", failure)
+	}
+	if result.RetryTranslationAfter <= 0 || result.RetryTranslationAfter > TranslationRetryBaseDelay {
+		t.Errorf("Expected a retry within %v, got %v", TranslationRetryBaseDelay, result.RetryTranslationAfter)
+	}
+
+	// English needs no translation
+	instance.Spec.Locale = "en-GB"
+	result, _ = ProcessCatFact(context.Background(), instance)
+	if result.TranslationErr != nil || instance.Status.Fact != "Cats are cool!" {
+		t.Errorf("Expected the English fact, got %+v (%v)", instance.Status, result.TranslationErr)
+	}
+	if instance.Status.TranslationFailure != nil {
+		t.Errorf("Expected the failure to be cleared, got %+v", instance.Status.TranslationFailure)
+	}
+}
+
+func TestProcessCatFactLocaleGenerated(t *testing.T) {
+	SetTranslators(&DictionaryTranslator{Translations: map[string]map[string]string{
+		"es": {"Cats sleep a lot": "Los gatos duermen mucho"},
+	}})
+	defer SetTranslators()
+	GetFactFromURL = func(context.Context, string) (string, error) {
+		return "Cats sleep a lot", nil
+	}
+
+	instance := &tacomoev1beta1.CatFact{
+		Spec: tacomoev1beta1.CatFactSpec{Locale: "es"},
+	}
+	if _, err := ProcessCatFact(context.Background(), instance); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if instance.Status.Fact != "Los gatos duermen mucho" || instance.Status.Category != CategoryBehavior {
+		t.Errorf("Expected a translated behavior fact, got %+v", instance.Status)
+	}
+
+	// Translated facts aren't translated again
+	SetTranslators()
+	result, _ := ProcessCatFact(context.Background(), instance)
+	if result.TranslationErr != nil || instance.Status.Fact != "Los gatos duermen mucho" {
+		t.Errorf("Expected the translation to be kept, got %+v (%v)", instance.Status, result.TranslationErr)
+	}
+}
+
+func TestTranslateFactBackoff(t *testing.T) {
+	translator := &DictionaryTranslator{Translations: map[string]map[string]string{}}
+	SetTranslators(translator)
+	defer SetTranslators()
+
+	instance := &tacomoev1beta1.CatFact{
+		Spec:   tacomoev1beta1.CatFactSpec{Fact: "Cats are cool!", Locale: "de"},
+		Status: tacomoev1beta1.CatFactStatus{Fact: "Cats are cool!"},
+	}
+	now := time.Now()
+	if err := TranslateFact(context.Background(), instance, now); !errors.Is(err, ErrNoTranslation) {
+		t.Fatalf("Expected ErrNoTranslation, got %v", err)
+	}
+
+	// Not retried until the delay passes
+	if err := TranslateFact(context.Background(), instance, now.Add(TranslationRetryBaseDelay/2)); err != nil {
+		t.Errorf("Expected the retry to be skipped, got %v", err)
+	}
+	if instance.Status.TranslationFailure.Attempts != 1 {
+		t.Errorf("Expected 1 attempt, got %d", instance.Status.TranslationFailure.Attempts)
+	}
+
+	// The delay doubles with every failed attempt
+	now = now.Add(TranslationRetryBaseDelay)
+	if err := TranslateFact(context.Background(), instance, now); !errors.Is(err, ErrNoTranslation) {
+		t.Fatalf("Expected ErrNoTranslation, got %v", err)
+	}
+	if after := TranslationRetryAfter(instance, now); instance.Status.TranslationFailure.Attempts != 2 || after != 2*TranslationRetryBaseDelay {
+		t.Errorf("Expected 2 attempts and a retry after %v, got %d and %v", 2*TranslationRetryBaseDelay,
+			instance.Status.TranslationFailure.Attempts, after)
+	}
+	instance.Status.TranslationFailure.Attempts = 20
+	if after := TranslationRetryAfter(instance, now); after != TranslationRetryMaxDelay {
+		t.Errorf("Expected a retry after %v, got %v", TranslationRetryMaxDelay, after)
+	}
+
+	// Changing the locale retries immediately, and a translation clears the failure
+	translator.Translations["fr"] = map[string]string{"Cats are cool!": "Les chats sont cool !"}
+	instance.Spec.Locale = "fr"
+	if err := TranslateFact(context.Background(), instance, now); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if instance.Status.Fact != "Les chats sont cool !" || instance.Status.TranslationFailure != nil {
+		t.Errorf("Expected a French fact and no failure, got %+v", instance.Status)
+	}
+}
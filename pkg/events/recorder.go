@@ -0,0 +1,62 @@
+/*
+Rate limited Kubernetes Events.
+
+The events.k8s.io recorder already aggregates repeated events about the same
+object into an event series. Creating many CatFacts at once still emits a
+distinct event per object, so events are also passed through a token bucket.
+Normal and Warning events have separate buckets so a flood of Normal events
+can't hide a Warning.
+*/
+
+package events
+
+import (
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/events"
+	"k8s.io/client-go/util/flowcontrol"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var eventsLog = ctrl.Log.WithName("events")
+
+const (
+	// Default sustained rate of events per type, per second
+	DefaultQPS float32 = 5
+
+	// Default number of events per type that can be emitted in a burst
+	DefaultBurst = 25
+)
+
+// RateLimitedRecorder is an events.EventRecorder that drops events once its
+// rate limit is exceeded.
+type RateLimitedRecorder struct {
+	recorder events.EventRecorder
+	normal   flowcontrol.RateLimiter
+	warning  flowcontrol.RateLimiter
+}
+
+var _ events.EventRecorder = &RateLimitedRecorder{}
+
+// Return a recorder that passes at most qps events per second, with bursts of
+// up to burst events, of each type to recorder
+func NewRateLimitedRecorder(recorder events.EventRecorder, qps float32, burst int) *RateLimitedRecorder {
+	return &RateLimitedRecorder{
+		recorder: recorder,
+		normal:   flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+		warning:  flowcontrol.NewTokenBucketRateLimiter(qps, burst),
+	}
+}
+
+// Eventf emits an event unless the rate limit for its type is exceeded.
+func (r *RateLimitedRecorder) Eventf(regarding runtime.Object, related runtime.Object, eventtype, reason, action, note string, args ...interface{}) {
+	limiter := r.normal
+	if eventtype == corev1.EventTypeWarning {
+		limiter = r.warning
+	}
+	if !limiter.TryAccept() {
+		eventsLog.V(1).Info("Rate limit exceeded, dropping event", "type", eventtype, "reason", reason)
+		return
+	}
+	r.recorder.Eventf(regarding, related, eventtype, reason, action, note, args...)
+}
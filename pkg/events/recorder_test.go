@@ -0,0 +1,29 @@
+package events
+
+import (
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/tools/events"
+
+	tacomoev1alpha1 "github.com/ryanmillerc/cat-facts-operator/api/v1alpha1"
+)
+
+func TestRateLimitedRecorder(t *testing.T) {
+	fake := events.NewFakeRecorder(100)
+	recorder := NewRateLimitedRecorder(fake, 0.001, 3)
+	catFact := &tacomoev1alpha1.CatFact{}
+
+	for i := 0; i < 10; i++ {
+		recorder.Eventf(catFact, nil, corev1.EventTypeNormal, "FactGenerated", "Generate", "Generated fact")
+	}
+	if len(fake.Events) != 3 {
+		t.Errorf("Expected 3 Normal events after the burst, got %d", len(fake.Events))
+	}
+
+	// Warnings have their own bucket
+	recorder.Eventf(catFact, nil, corev1.EventTypeWarning, "InvalidIconName", "Validate", "Invalid icon")
+	if len(fake.Events) != 4 {
+		t.Errorf("Expected Warning event to be emitted after Normal burst, got %d events", len(fake.Events))
+	}
+}
@@ -0,0 +1,217 @@
+/*
+Feature gates for operator capabilities that are still being rolled out.
+
+Each gate has a stage and a default. Alpha gates are off by default, beta
+gates are on by default, and GA gates are always on and can't be disabled.
+Gates are set with the --feature-gates flag or the featureGates section of the
+config file:
+
+	--feature-gates=InvalidIconFallback=true,CatFactEvents=false
+*/
+
+package features
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Feature is the name of a feature gate
+type Feature string
+
+// Stage of a feature
+type Stage string
+
+const (
+	Alpha Stage = "Alpha"
+	Beta  Stage = "Beta"
+	GA    Stage = "GA"
+)
+
+const (
+	// Emit Events about CatFacts from the CatFact controller
+	CatFactEvents Feature = "CatFactEvents"
+
+	// Replace an invalid iconName with a random allowed one instead of
+	// rejecting the CatFact
+	InvalidIconFallback Feature = "InvalidIconFallback"
+
+	// Register the operator backend API as a ConsolePlugin proxy
+	ConsolePluginBackendProxy Feature = "ConsolePluginBackendProxy"
+
+	// Roll the console plugin when its serving certificate rotates
+	ServingCertRotation Feature = "ServingCertRotation"
+)
+
+// FeatureSpec describes a feature gate
+type FeatureSpec struct {
+	Default     bool
+	Stage       Stage
+	Description string
+}
+
+var defaultFeatures = map[Feature]FeatureSpec{
+	CatFactEvents: {
+		Default:     true,
+		Stage:       Beta,
+		Description: "Emit Events about CatFacts",
+	},
+	InvalidIconFallback: {
+		Default:     false,
+		Stage:       Alpha,
+		Description: "Replace an invalid iconName with a random allowed one instead of rejecting the CatFact",
+	},
+	ConsolePluginBackendProxy: {
+		Default:     true,
+		Stage:       Beta,
+		Description: "Register the operator backend API as a ConsolePlugin proxy",
+	},
+	ServingCertRotation: {
+		Default:     true,
+		Stage:       GA,
+		Description: "Roll the console plugin when its serving certificate rotates",
+	},
+}
+
+// FeatureGate is a set of known features and whether they're enabled. It
+// implements flag.Value.
+type FeatureGate struct {
+	mu      sync.RWMutex
+	known   map[Feature]FeatureSpec
+	enabled map[Feature]bool
+}
+
+// Return a FeatureGate with features set to their defaults
+func NewFeatureGate(known map[Feature]FeatureSpec) *FeatureGate {
+	return &FeatureGate{
+		known:   maps.Clone(known),
+		enabled: map[Feature]bool{},
+	}
+}
+
+// DefaultFeatureGate holds the operator's feature gates
+var DefaultFeatureGate = NewFeatureGate(defaultFeatures)
+
+// Return true if feature is enabled in DefaultFeatureGate
+func Enabled(feature Feature) bool {
+	return DefaultFeatureGate.Enabled(feature)
+}
+
+// Return true if feature is enabled. Unknown features are disabled.
+func (g *FeatureGate) Enabled(feature Feature) bool {
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	if enabled, ok := g.enabled[feature]; ok {
+		return enabled
+	}
+	return g.known[feature].Default
+}
+
+// Set features from a map of feature names to values. Nothing is changed if
+// any feature is unknown or can't be changed.
+func (g *FeatureGate) SetFromMap(values map[string]bool) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	for name, value := range values {
+		spec, ok := g.known[Feature(name)]
+		if !ok {
+			return fmt.Errorf("unknown feature gate %q", name)
+		}
+		if spec.Stage == GA && value != spec.Default {
+			return fmt.Errorf("feature gate %s is GA and can't be set to %t", name, value)
+		}
+	}
+	for name, value := range values {
+		g.enabled[Feature(name)] = value
+	}
+	return nil
+}
+
+// Set features from a comma-separated list of name=bool pairs
+func (g *FeatureGate) Set(value string) error {
+	values := map[string]bool{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		name, v, ok := strings.Cut(pair, "=")
+		if !ok {
+			return fmt.Errorf("missing bool value for feature gate %s", pair)
+		}
+		enabled, err := strconv.ParseBool(strings.TrimSpace(v))
+		if err != nil {
+			return fmt.Errorf("invalid value %q for feature gate %s", v, name)
+		}
+		values[strings.TrimSpace(name)] = enabled
+	}
+	return g.SetFromMap(values)
+}
+
+// Return features explicitly set, as name=bool pairs
+func (g *FeatureGate) String() string {
+	if g == nil {
+		return ""
+	}
+	g.mu.RLock()
+	defer g.mu.RUnlock()
+	pairs := []string{}
+	for feature, enabled := range g.enabled {
+		pairs = append(pairs, fmt.Sprintf("%s=%t", feature, enabled))
+	}
+	slices.Sort(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// FeatureStatus is the state of a single feature gate
+type FeatureStatus struct {
+	Name        string `json:"name"`
+	Stage       Stage  `json:"stage"`
+	Default     bool   `json:"default"`
+	Enabled     bool   `json:"enabled"`
+	Description string `json:"description"`
+}
+
+// Return the state of every known feature, sorted by name
+func (g *FeatureGate) Status() []FeatureStatus {
+	names := []string{}
+	g.mu.RLock()
+	for feature := range g.known {
+		names = append(names, string(feature))
+	}
+	g.mu.RUnlock()
+	slices.Sort(names)
+
+	status := make([]FeatureStatus, 0, len(names))
+	for _, name := range names {
+		spec := g.known[Feature(name)]
+		status = append(status, FeatureStatus{
+			Name:        name,
+			Stage:       spec.Stage,
+			Default:     spec.Default,
+			Enabled:     g.Enabled(Feature(name)),
+			Description: spec.Description,
+		})
+	}
+	return status
+}
+
+// Return true if name is a known feature
+func (g *FeatureGate) Known(name string) bool {
+	_, ok := g.known[Feature(name)]
+	return ok
+}
+
+// Return a usage string listing the known features for the --feature-gates
+// flag
+func (g *FeatureGate) Usage() string {
+	lines := []string{"Comma-separated list of feature=bool pairs. Known features:"}
+	for _, status := range g.Status() {
+		lines = append(lines, fmt.Sprintf("%s=true|false (%s - default=%t)", status.Name, status.Stage, status.Default))
+	}
+	return strings.Join(lines, "\n")
+}
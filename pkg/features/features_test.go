@@ -0,0 +1,70 @@
+package features
+
+import (
+	"testing"
+)
+
+func newTestFeatureGate() *FeatureGate {
+	return NewFeatureGate(map[Feature]FeatureSpec{
+		"AlphaFeature": {Default: false, Stage: Alpha},
+		"BetaFeature":  {Default: true, Stage: Beta},
+		"GAFeature":    {Default: true, Stage: GA},
+	})
+}
+
+func TestDefaults(t *testing.T) {
+	g := newTestFeatureGate()
+	if g.Enabled("AlphaFeature") || !g.Enabled("BetaFeature") || !g.Enabled("GAFeature") {
+		t.Errorf("Expected features to default to their spec, got %+v", g.Status())
+	}
+	if g.Enabled("UnknownFeature") {
+		t.Errorf("Expected unknown features to be disabled")
+	}
+}
+
+func TestSet(t *testing.T) {
+	g := newTestFeatureGate()
+	if err := g.Set("AlphaFeature=true, BetaFeature=false"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !g.Enabled("AlphaFeature") || g.Enabled("BetaFeature") {
+		t.Errorf("Expected features to be set, got %+v", g.Status())
+	}
+	if g.String() != "AlphaFeature=true,BetaFeature=false" {
+		t.Errorf("Unexpected String() %q", g.String())
+	}
+
+	invalid := []string{
+		"UnknownFeature=true",
+		"AlphaFeature",
+		"AlphaFeature=maybe",
+		"GAFeature=false",
+	}
+	for _, value := range invalid {
+		if err := g.Set(value); err == nil {
+			t.Errorf("Expected an error setting %q", value)
+		}
+	}
+
+	// Nothing is changed if any feature is invalid
+	if err := g.Set("AlphaFeature=false,UnknownFeature=true"); err == nil {
+		t.Errorf("Expected an error")
+	}
+	if !g.Enabled("AlphaFeature") {
+		t.Errorf("Expected AlphaFeature to be unchanged after an invalid Set")
+	}
+}
+
+func TestDefaultFeatureGate(t *testing.T) {
+	for _, status := range DefaultFeatureGate.Status() {
+		if status.Description == "" {
+			t.Errorf("Expected %s to have a description", status.Name)
+		}
+		if status.Stage == Alpha && status.Default {
+			t.Errorf("Expected alpha feature %s to be disabled by default", status.Name)
+		}
+		if status.Stage != Alpha && !status.Default {
+			t.Errorf("Expected %s feature %s to be enabled by default", status.Stage, status.Name)
+		}
+	}
+}
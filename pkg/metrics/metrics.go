@@ -0,0 +1,198 @@
+/*
+Custom Prometheus metrics for the Cat Facts Operator.
+
+Metrics are registered with controller-runtime's registry, so they are served
+on the manager's metrics endpoint next to the controller-runtime defaults.
+*/
+
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+var metricsLog = ctrl.Log.WithName("metrics")
+
+// Source label for facts that came from the placeholder instead of a provider
+const SourcePlaceholder = "placeholder"
+
+// Reason labels for facts that fell back to the placeholder
+const (
+	// The provider failed
+	FallbackProviderError = "provider_error"
+	// Moderation rejected every fact from the provider
+	FallbackModerated = "moderated"
+)
+
+var (
+	// Facts set on CatFacts, by the provider that generated them
+	FactsGenerated = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "catfacts_facts_generated_total",
+			Help: "Number of facts generated for CatFacts, by source",
+		},
+		[]string{"source"},
+	)
+
+	// Time taken by fact providers
+	ProviderLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "catfacts_provider_request_duration_seconds",
+			Help:    "Time taken to get a fact from a fact provider",
+			Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10},
+		},
+		[]string{"provider"},
+	)
+
+	// Facts that fell back to the placeholder, by why
+	FallbackFacts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "catfacts_fallback_total",
+			Help: "Number of times the placeholder fact was used because a provider failed or moderation rejected every fact",
+		},
+		[]string{"reason"},
+	)
+
+	// CatFacts rejected for an invalid iconName
+	InvalidIcons = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "catfacts_invalid_icon_total",
+			Help: "Number of times a CatFact was rejected for an invalid iconName",
+		},
+	)
+
+	// HTTP responses from the fact API
+	FactAPIResponses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "catfacts_fact_api_responses_total",
+			Help: "HTTP responses from the fact API, by status code. Requests that failed without a response use code \"error\".",
+		},
+		[]string{"code"},
+	)
+
+	// Facts translated into CatFacts' spec.locale
+	Translations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "catfacts_translations_total",
+			Help: "Number of fact translations, by translator and result: translated, error, or missing if no translator had one",
+		},
+		[]string{"translator", "result"},
+	)
+
+	// Fetched facts rejected by a moderator
+	FactsRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "catfacts_facts_rejected_total",
+			Help: "Number of fetched facts rejected by moderation, by moderator",
+		},
+		[]string{"moderator"},
+	)
+
+	// CatFacts counted against each namespace's quota. Kept up to date from
+	// informer events instead of counted on every scrape.
+	QuotaUsed = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "catfacts_quota_used",
+			Help: "Number of CatFacts counted against the namespace's quota, by namespace",
+		},
+		[]string{"namespace"},
+	)
+
+	// CatFacts the validating webhook refused to create because the
+	// namespace was at its quota
+	QuotaRejections = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "catfacts_quota_rejections_total",
+			Help: "Number of CatFacts rejected because their namespace was at its quota, by namespace",
+		},
+		[]string{"namespace"},
+	)
+
+	// Expiry of the serving certificate mounted by the console plugin
+	ConsolePluginCertExpiry = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "catfacts_console_plugin_cert_expiry_timestamp_seconds",
+			Help: "Unix time the console plugin's serving certificate expires",
+		},
+	)
+)
+
+func init() {
+	ctrlmetrics.Registry.MustRegister(
+		FactsGenerated,
+		ProviderLatency,
+		FallbackFacts,
+		InvalidIcons,
+		FactAPIResponses,
+		Translations,
+		FactsRejected,
+		QuotaUsed,
+		QuotaRejections,
+		ConsolePluginCertExpiry,
+	)
+}
+
+// Record the status code of a fact API response
+func ObserveFactAPIResponse(statusCode int) {
+	FactAPIResponses.WithLabelValues(strconv.Itoa(statusCode)).Inc()
+}
+
+// Record a fact API request that failed without a response
+func ObserveFactAPIError() {
+	FactAPIResponses.WithLabelValues("error").Inc()
+}
+
+// How long a CatFact count may take before the scrape gives up on it
+const collectTimeout = 5 * time.Second
+
+var catFactsDesc = prometheus.NewDesc(
+	"catfacts_catfacts",
+	"Number of CatFacts, by namespace and iconName",
+	[]string{"namespace", "icon_name"},
+	nil,
+)
+
+// catFactCollector counts CatFacts on every scrape. Reading from the
+// manager's cache keeps scrapes cheap.
+type catFactCollector struct {
+	reader client.Reader
+}
+
+// Register a collector that reports the number of CatFacts per namespace and
+// iconName using reader
+func RegisterCatFactCollector(reader client.Reader) error {
+	return ctrlmetrics.Registry.Register(&catFactCollector{reader: reader})
+}
+
+func (c *catFactCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- catFactsDesc
+}
+
+func (c *catFactCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), collectTimeout)
+	defer cancel()
+
+	var catFacts tacomoev1beta1.CatFactList
+	if err := c.reader.List(ctx, &catFacts); err != nil {
+		metricsLog.V(1).Info("Unable to count CatFacts", "error", err.Error())
+		return
+	}
+
+	type key struct{ namespace, iconName string }
+	counts := map[key]int{}
+	for _, catFact := range catFacts.Items {
+		counts[key{catFact.Namespace, catFact.Status.IconName}]++
+	}
+	for k, count := range counts {
+		ch <- prometheus.MustNewConstMetric(catFactsDesc, prometheus.GaugeValue, float64(count), k.namespace, k.iconName)
+	}
+}
@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Return the value of a counter or gauge
+func value(t *testing.T, metric prometheus.Metric) float64 {
+	t.Helper()
+	var m dto.Metric
+	if err := metric.Write(&m); err != nil {
+		t.Fatalf("Unable to read metric: %v", err)
+	}
+	if m.Counter != nil {
+		return m.Counter.GetValue()
+	}
+	return m.Gauge.GetValue()
+}
+
+func TestObserveFactAPIResponse(t *testing.T) {
+	before := value(t, FactAPIResponses.WithLabelValues("503"))
+	ObserveFactAPIResponse(503)
+	if after := value(t, FactAPIResponses.WithLabelValues("503")); after != before+1 {
+		t.Errorf("Expected 503 count to increase by 1, got %v -> %v", before, after)
+	}
+
+	before = value(t, FactAPIResponses.WithLabelValues("error"))
+	ObserveFactAPIError()
+	if after := value(t, FactAPIResponses.WithLabelValues("error")); after != before+1 {
+		t.Errorf("Expected error count to increase by 1, got %v -> %v", before, after)
+	}
+}
+
+func TestCatFactCollector(t *testing.T) {
+	scheme := runtime.NewScheme()
+	tacomoev1beta1.AddToScheme(scheme)
+	catFact := func(namespace, name, iconName string) *tacomoev1beta1.CatFact {
+		return &tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace},
+			Status:     tacomoev1beta1.CatFactStatus{IconName: iconName},
+		}
+	}
+	reader := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		catFact("one", "a", "Joy"),
+		catFact("one", "b", "Joy"),
+		catFact("one", "c", "Evil"),
+		catFact("two", "a", "Joy"),
+	).Build()
+
+	ch := make(chan prometheus.Metric, 10)
+	(&catFactCollector{reader: reader}).Collect(ch)
+	close(ch)
+
+	got := map[string]float64{}
+	for metric := range ch {
+		var m dto.Metric
+		if err := metric.Write(&m); err != nil {
+			t.Fatalf("Unable to read metric: %v", err)
+		}
+		labels := map[string]string{}
+		for _, l := range m.Label {
+			labels[l.GetName()] = l.GetValue()
+		}
+		got[labels["namespace"]+"/"+labels["icon_name"]] = m.Gauge.GetValue()
+	}
+
+	want := map[string]float64{"one/Joy": 2, "one/Evil": 1, "two/Joy": 1}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("Expected %s to be %v, got %v", k, v, got[k])
+		}
+	}
+}
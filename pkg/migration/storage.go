@@ -0,0 +1,166 @@
+/*
+Migration of stored CatFacts to the storage version.
+
+The CatFact CRD serves v1alpha1 and v1beta1 and stores v1beta1. Objects
+written before v1beta1 was the storage version stay stored as v1alpha1 until
+they're written again, and the CRD's status.storedVersions keeps listing
+v1alpha1 until nothing is stored in it anymore. v1alpha1 can only be removed
+from the CRD once it's gone from storedVersions.
+
+StorageVersionMigrator rewrites every CatFact once with an empty patch, which
+makes the API server store it in the storage version, and then removes the
+old versions from storedVersions.
+
+When the operator only watches some namespaces it can't list CatFacts in the
+others, so only the watched namespaces are rewritten and storedVersions is
+left for a cluster administrator to update once every CatFact is migrated.
+*/
+
+package migration
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/util/retry"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+var migrationLog = ctrl.Log.WithName("migration")
+
+const (
+	// Name of the CatFact CRD
+	CatFactCRDName = "catfacts.ryanmillerc.github.io"
+
+	// Default time between attempts when a migration fails, e.g. because
+	// the conversion webhook isn't reachable yet
+	DefaultRetryInterval = 30 * time.Second
+)
+
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions,verbs=get;list;watch
+//+kubebuilder:rbac:groups=apiextensions.k8s.io,resources=customresourcedefinitions/status,verbs=update;patch
+
+// StorageVersionMigrator migrates stored CatFacts to the storage version,
+// v1beta1. It runs once on the leader and retries until it succeeds.
+type StorageVersionMigrator struct {
+	// Client used to write CatFacts and the CRD status
+	Client client.Client
+
+	// Reader used to list CatFacts and read the CRD. Use one that doesn't
+	// read from the cache, so CatFacts outside the cache are migrated too.
+	Reader client.Reader
+
+	// Namespaces to migrate CatFacts in. Empty means all namespaces. When
+	// set, status.storedVersions isn't changed because CatFacts in other
+	// namespaces may still be stored in an old version.
+	Namespaces []string
+
+	// Defaults to DefaultRetryInterval
+	RetryInterval time.Duration
+}
+
+var _ manager.Runnable = &StorageVersionMigrator{}
+var _ manager.LeaderElectionRunnable = &StorageVersionMigrator{}
+
+// Only the leader migrates
+func (m *StorageVersionMigrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Migrate until it succeeds or ctx is cancelled
+func (m *StorageVersionMigrator) Start(ctx context.Context) error {
+	interval := m.RetryInterval
+	if interval == 0 {
+		interval = DefaultRetryInterval
+	}
+	err := wait.PollUntilContextCancel(ctx, interval, true, func(ctx context.Context) (bool, error) {
+		if err := m.Migrate(ctx); err != nil {
+			migrationLog.Error(err, "unable to migrate CatFacts to the storage version, retrying", "interval", interval)
+			return false, nil
+		}
+		return true, nil
+	})
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// Rewrite every CatFact in the storage version if the CRD still lists other
+// stored versions, then remove them from the CRD's status.storedVersions
+func (m *StorageVersionMigrator) Migrate(ctx context.Context) error {
+	storageVersion := tacomoev1beta1.GroupVersion.Version
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := m.Reader.Get(ctx, types.NamespacedName{Name: CatFactCRDName}, crd); err != nil {
+		return err
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storageVersion}) {
+		migrationLog.V(1).Info("CatFacts are stored in the storage version", "version", storageVersion)
+		return nil
+	}
+
+	migrationLog.Info("migrating CatFacts to the storage version",
+		"storedVersions", crd.Status.StoredVersions, "version", storageVersion)
+	catFacts, err := m.listCatFacts(ctx)
+	if err != nil {
+		return err
+	}
+	for i := range catFacts {
+		// An empty patch doesn't change the object, but the API server
+		// still writes it because it's encoded in a different version
+		catFact := &catFacts[i]
+		catFact.SetGroupVersionKind(tacomoev1beta1.GroupVersion.WithKind("CatFact"))
+		err := m.Client.Patch(ctx, catFact, client.RawPatch(types.MergePatchType, []byte("{}")))
+		if err != nil && !kerrors.IsNotFound(err) {
+			return err
+		}
+	}
+
+	if len(m.Namespaces) > 0 {
+		migrationLog.Info("migrated CatFacts in the watched namespaces to the storage version, leaving storedVersions unchanged",
+			"count", len(catFacts), "namespaces", m.Namespaces, "version", storageVersion)
+		return nil
+	}
+
+	err = retry.RetryOnConflict(retry.DefaultBackoff, func() error {
+		if err := m.Reader.Get(ctx, types.NamespacedName{Name: CatFactCRDName}, crd); err != nil {
+			return err
+		}
+		crd.Status.StoredVersions = []string{storageVersion}
+		return m.Client.Status().Update(ctx, crd)
+	})
+	if err != nil {
+		return err
+	}
+	migrationLog.Info("migrated CatFacts to the storage version", "count", len(catFacts), "version", storageVersion)
+	return nil
+}
+
+// List the CatFacts to migrate: all of them, or those in m.Namespaces
+func (m *StorageVersionMigrator) listCatFacts(ctx context.Context) ([]metav1.PartialObjectMetadata, error) {
+	namespaces := m.Namespaces
+	if len(namespaces) == 0 {
+		namespaces = []string{metav1.NamespaceAll}
+	}
+	var items []metav1.PartialObjectMetadata
+	for _, namespace := range namespaces {
+		var catFacts metav1.PartialObjectMetadataList
+		catFacts.SetGroupVersionKind(tacomoev1beta1.GroupVersion.WithKind("CatFactList"))
+		if err := m.Reader.List(ctx, &catFacts, client.InNamespace(namespace)); err != nil {
+			return nil, err
+		}
+		items = append(items, catFacts.Items...)
+	}
+	return items, nil
+}
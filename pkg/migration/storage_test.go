@@ -0,0 +1,104 @@
+package migration
+
+import (
+	"context"
+	"slices"
+	"testing"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func newTestClient(t *testing.T, storedVersions []string, patched *[]string) client.Client {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := tacomoev1beta1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: CatFactCRDName},
+		Status:     apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(
+			crd,
+			&tacomoev1beta1.CatFact{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "cat-1"}},
+			&tacomoev1beta1.CatFact{ObjectMeta: metav1.ObjectMeta{Namespace: "other", Name: "cat-2"}},
+		).
+		WithStatusSubresource(crd).
+		WithInterceptorFuncs(interceptor.Funcs{
+			Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+				*patched = append(*patched, obj.GetNamespace()+"/"+obj.GetName())
+				return c.Patch(ctx, obj, patch, opts...)
+			},
+		}).
+		Build()
+}
+
+func TestMigrate(t *testing.T) {
+	var patched []string
+	c := newTestClient(t, []string{"v1alpha1", "v1beta1"}, &patched)
+	m := &StorageVersionMigrator{Client: c, Reader: c}
+	if err := m.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	slices.Sort(patched)
+	if !slices.Equal(patched, []string{"default/cat-1", "other/cat-2"}) {
+		t.Errorf("Expected every CatFact to be rewritten, got %v", patched)
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: CatFactCRDName}, crd); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(crd.Status.StoredVersions, []string{"v1beta1"}) {
+		t.Errorf("Expected storedVersions [v1beta1], got %v", crd.Status.StoredVersions)
+	}
+}
+
+func TestMigrateNamespaces(t *testing.T) {
+	var patched []string
+	c := newTestClient(t, []string{"v1alpha1", "v1beta1"}, &patched)
+	m := &StorageVersionMigrator{Client: c, Reader: c, Namespaces: []string{"default"}}
+	if err := m.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if !slices.Equal(patched, []string{"default/cat-1"}) {
+		t.Errorf("Expected only CatFacts in the watched namespaces to be rewritten, got %v", patched)
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := c.Get(context.Background(), types.NamespacedName{Name: CatFactCRDName}, crd); err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(crd.Status.StoredVersions, []string{"v1alpha1", "v1beta1"}) {
+		t.Errorf("Expected storedVersions to be unchanged, got %v", crd.Status.StoredVersions)
+	}
+}
+
+func TestMigrateAlreadyMigrated(t *testing.T) {
+	var patched []string
+	c := newTestClient(t, []string{"v1beta1"}, &patched)
+	m := &StorageVersionMigrator{Client: c, Reader: c}
+	if err := m.Migrate(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if len(patched) > 0 {
+		t.Errorf("Expected no CatFacts to be rewritten, got %v", patched)
+	}
+}
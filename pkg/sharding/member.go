@@ -0,0 +1,163 @@
+package sharding
+
+import (
+	"context"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+var shardingLog = ctrl.Log.WithName("sharding")
+
+const (
+	// Label on CatFacts naming the member that reconciles them
+	ShardLabel = "catfacts.ryanmillerc.github.io/shard"
+
+	// Label on member Leases
+	MemberLeaseLabel = "catfacts.ryanmillerc.github.io/shard-member"
+
+	// Value of MemberLeaseLabel on Leases of CatFact controller members
+	MemberLeaseGroup = "catfact-controller"
+
+	// Prefix of member Lease names. The member identity is appended.
+	MemberLeasePrefix = "catfact-shard-"
+
+	// Default time without a renewal after which a member is considered gone
+	DefaultLeaseDuration = 15 * time.Second
+
+	// Default interval between Lease renewals
+	DefaultRenewInterval = 5 * time.Second
+)
+
+// Return the selector for CatFacts owned by the member with identity
+func LabelSelector(identity string) labels.Selector {
+	return labels.SelectorFromSet(labels.Set{ShardLabel: identity})
+}
+
+// Return the selector for member Leases
+func MemberLeaseSelector() labels.Selector {
+	return labels.SelectorFromSet(labels.Set{MemberLeaseLabel: MemberLeaseGroup})
+}
+
+// Member announces a replica as a shard member by holding a Lease. It runs on
+// every replica, not only the leader. The Lease is deleted when the replica
+// stops so its CatFacts are handed over without waiting for it to expire.
+type Member struct {
+	// Client used to read and write the Lease. Use a client that doesn't
+	// read from the cache, Leases aren't cached on every replica.
+	Client client.Client
+
+	// Namespace of the Lease, usually the operator's namespace
+	Namespace string
+
+	// Identity of the member, usually the pod name. CatFacts owned by this
+	// member are labelled with it.
+	Identity string
+
+	// Defaults to DefaultLeaseDuration
+	LeaseDuration time.Duration
+
+	// Defaults to DefaultRenewInterval
+	RenewInterval time.Duration
+}
+
+var _ manager.Runnable = &Member{}
+var _ manager.LeaderElectionRunnable = &Member{}
+
+// Every replica is a member, not only the leader
+func (m *Member) NeedLeaderElection() bool {
+	return false
+}
+
+// Renew the Lease until ctx is cancelled, then delete it
+func (m *Member) Start(ctx context.Context) error {
+	if m.LeaseDuration == 0 {
+		m.LeaseDuration = DefaultLeaseDuration
+	}
+	if m.RenewInterval == 0 {
+		m.RenewInterval = DefaultRenewInterval
+	}
+	shardingLog.Info("joining shard ring", "identity", m.Identity, "namespace", m.Namespace)
+
+	ticker := time.NewTicker(m.RenewInterval)
+	defer ticker.Stop()
+	for {
+		if err := m.renew(ctx); err != nil {
+			shardingLog.Error(err, "unable to renew member lease", "identity", m.Identity)
+		}
+		select {
+		case <-ctx.Done():
+			return m.release()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Create or renew the member Lease
+func (m *Member) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := &coordinationv1.Lease{}
+	err := m.Client.Get(ctx, client.ObjectKey{Namespace: m.Namespace, Name: m.leaseName()}, lease)
+	if kerrors.IsNotFound(err) {
+		lease = &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      m.leaseName(),
+				Namespace: m.Namespace,
+				Labels:    map[string]string{MemberLeaseLabel: MemberLeaseGroup},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(m.Identity),
+				LeaseDurationSeconds: ptr.To(int32(m.LeaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return m.Client.Create(ctx, lease)
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.HolderIdentity = ptr.To(m.Identity)
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(m.LeaseDuration.Seconds()))
+	lease.Spec.RenewTime = &now
+	return m.Client.Update(ctx, lease)
+}
+
+// Delete the member Lease so the Sharder moves this member's CatFacts right
+// away
+func (m *Member) release() error {
+	ctx, cancel := context.WithTimeout(context.Background(), m.RenewInterval)
+	defer cancel()
+	shardingLog.Info("leaving shard ring", "identity", m.Identity)
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Name: m.leaseName(), Namespace: m.Namespace},
+	}
+	return client.IgnoreNotFound(m.Client.Delete(ctx, lease))
+}
+
+func (m *Member) leaseName() string {
+	return MemberLeasePrefix + m.Identity
+}
+
+// Return the identities of members whose Leases haven't expired at now
+func LiveMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	var members []string
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expires := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if now.Before(expires) {
+			members = append(members, *spec.HolderIdentity)
+		}
+	}
+	return members
+}
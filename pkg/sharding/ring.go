@@ -0,0 +1,97 @@
+/*
+Hash sharding of CatFacts across operator replicas.
+
+Sharding is optional. When it's enabled every replica reconciles only the
+CatFacts in its shard, instead of a single leader reconciling all of them:
+
+  - Each replica announces itself with a Member Lease in the operator's
+    namespace and keeps renewing it.
+  - The leader runs the Sharder. It places the live members on a consistent
+    hash ring, hashes each CatFact's namespace/name onto the ring, and labels
+    the CatFact with ShardLabel set to the member that owns that hash range.
+  - Each replica's cache only holds CatFacts labelled with its own identity,
+    see LabelSelector.
+
+When a replica comes or goes, its Lease appears or expires and the Sharder
+relabels the CatFacts whose owner changed. With a consistent hash ring only
+about 1/n of the CatFacts move.
+*/
+
+package sharding
+
+import (
+	"hash/fnv"
+	"slices"
+	"sort"
+	"strconv"
+)
+
+// Number of points each member has on the ring. More points spread CatFacts
+// more evenly between members.
+const TokensPerMember = 100
+
+// Ring is a consistent hash ring. Each member owns the hash ranges that end at
+// its tokens.
+type Ring struct {
+	members []string
+	tokens  []uint64
+	owners  map[uint64]string
+}
+
+// Return a ring with the given members. The order of members doesn't matter.
+func NewRing(members []string) *Ring {
+	r := &Ring{
+		members: slices.Sorted(slices.Values(members)),
+		owners:  make(map[uint64]string, len(members)*TokensPerMember),
+	}
+	for _, member := range r.members {
+		for i := 0; i < TokensPerMember; i++ {
+			token := hash(member + "#" + strconv.Itoa(i))
+			if _, taken := r.owners[token]; taken {
+				continue
+			}
+			r.owners[token] = member
+			r.tokens = append(r.tokens, token)
+		}
+	}
+	slices.Sort(r.tokens)
+	return r
+}
+
+// Return the sorted members of the ring
+func (r *Ring) Members() []string {
+	return r.members
+}
+
+// Return the member that owns key, or "" if the ring is empty
+func (r *Ring) Owner(key string) string {
+	if len(r.tokens) == 0 {
+		return ""
+	}
+	h := hash(key)
+	i := sort.Search(len(r.tokens), func(i int) bool { return r.tokens[i] >= h })
+	if i == len(r.tokens) {
+		i = 0
+	}
+	return r.owners[r.tokens[i]]
+}
+
+// Return the ring key of a CatFact
+func Key(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// Hash s with FNV-1a. FNV barely changes the high bits for keys that only
+// differ in their last byte, like the tokens of a member, so the result is
+// mixed with the MurmurHash3 finalizer to spread keys around the ring.
+func hash(s string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(s))
+	x := h.Sum64()
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
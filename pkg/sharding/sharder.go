@@ -0,0 +1,174 @@
+package sharding
+
+import (
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/rest"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+// Sharder assigns CatFacts to shard members. It runs on the leader.
+type Sharder struct {
+	// Client used to label CatFacts
+	Client client.Client
+
+	// Cache with every CatFact's metadata and the member Leases. It must not
+	// be filtered by LabelSelector, see NewCache.
+	Cache cache.Cache
+
+	// Namespace of the member Leases
+	Namespace string
+
+	// Current ring, rebuilt when members join or leave
+	mu   sync.Mutex
+	ring *Ring
+}
+
+// Return a cache for the Sharder. It holds metadata of CatFacts in
+// namespaces, or all namespaces if empty, and member Leases in
+// leaseNamespace. Add it to the manager to start it on every replica; the
+// quota webhook counts CatFacts in it too.
+func NewCache(config *rest.Config, scheme *runtime.Scheme, namespaces []string, leaseNamespace string) (cache.Cache, error) {
+	opts := cache.Options{
+		Scheme: scheme,
+		ByObject: map[client.Object]cache.ByObject{
+			&coordinationv1.Lease{}: {
+				Namespaces: map[string]cache.Config{leaseNamespace: {}},
+				Label:      MemberLeaseSelector(),
+			},
+		},
+	}
+	if len(namespaces) > 0 {
+		opts.DefaultNamespaces = map[string]cache.Config{}
+		for _, ns := range namespaces {
+			opts.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	return cache.New(config, opts)
+}
+
+// Return an empty CatFact metadata object
+func catFactMetadata() *metav1.PartialObjectMetadata {
+	obj := &metav1.PartialObjectMetadata{}
+	obj.SetGroupVersionKind(tacomoev1beta1.GroupVersion.WithKind("CatFact"))
+	return obj
+}
+
+// Label a CatFact with the member that owns it
+func (s *Sharder) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ring, err := s.currentRing(ctx)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	owner := ring.Owner(Key(req.Namespace, req.Name))
+	if owner == "" {
+		// No members yet. CatFacts are assigned when the first one joins.
+		return ctrl.Result{}, nil
+	}
+
+	obj := catFactMetadata()
+	if err := s.Cache.Get(ctx, req.NamespacedName, obj); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if obj.GetLabels()[ShardLabel] == owner {
+		return ctrl.Result{}, nil
+	}
+
+	shardingLog.V(1).Info("assigning CatFact", "namespace", req.Namespace, "name", req.Name,
+		"from", obj.GetLabels()[ShardLabel], "to", owner)
+	patch := client.MergeFrom(obj.DeepCopy())
+	labels := obj.GetLabels()
+	if labels == nil {
+		labels = map[string]string{}
+	}
+	labels[ShardLabel] = owner
+	obj.SetLabels(labels)
+	err = s.Client.Patch(ctx, obj, patch)
+	if kerrors.IsNotFound(err) {
+		return ctrl.Result{}, nil
+	}
+	return ctrl.Result{}, err
+}
+
+// Return the ring for the current members, building it if needed
+func (s *Sharder) currentRing(ctx context.Context) (*Ring, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.ring != nil {
+		return s.ring, nil
+	}
+	members, err := s.liveMembers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.ring = NewRing(members)
+	return s.ring, nil
+}
+
+func (s *Sharder) liveMembers(ctx context.Context) ([]string, error) {
+	var leases coordinationv1.LeaseList
+	if err := s.Cache.List(ctx, &leases, client.InNamespace(s.Namespace), client.MatchingLabelsSelector{Selector: MemberLeaseSelector()}); err != nil {
+		return nil, err
+	}
+	return LiveMembers(leases.Items, time.Now()), nil
+}
+
+// Called on every member Lease event. Members renew their Leases regularly,
+// so this also notices members whose Leases expired. If the members changed,
+// the ring is rebuilt and every CatFact is requeued to be rebalanced.
+func (s *Sharder) rebalance(ctx context.Context, _ *coordinationv1.Lease) []reconcile.Request {
+	members, err := s.liveMembers(ctx)
+	if err != nil {
+		shardingLog.Error(err, "unable to list shard members")
+		return nil
+	}
+
+	s.mu.Lock()
+	changed := s.ring == nil || !slices.Equal(s.ring.Members(), slices.Sorted(slices.Values(members)))
+	if changed {
+		s.ring = NewRing(members)
+	}
+	s.mu.Unlock()
+	if !changed {
+		return nil
+	}
+
+	shardingLog.Info("shard members changed, rebalancing CatFacts", "members", members)
+	var catFacts metav1.PartialObjectMetadataList
+	catFacts.SetGroupVersionKind(tacomoev1beta1.GroupVersion.WithKind("CatFactList"))
+	if err := s.Cache.List(ctx, &catFacts); err != nil {
+		shardingLog.Error(err, "unable to list CatFacts to rebalance")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(catFacts.Items))
+	for _, catFact := range catFacts.Items {
+		requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(&catFact)})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the Sharder with the Manager. The Sharder's cache
+// must also be added to the Manager.
+func (s *Sharder) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		Named("catfact-sharder").
+		WatchesRawSource(source.Kind(s.Cache, catFactMetadata(),
+			&handler.TypedEnqueueRequestForObject[*metav1.PartialObjectMetadata]{})).
+		WatchesRawSource(source.Kind(s.Cache, &coordinationv1.Lease{},
+			handler.TypedEnqueueRequestsFromMapFunc(s.rebalance))).
+		Complete(s)
+}
@@ -0,0 +1,194 @@
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	tacomoev1beta1 "github.com/ryanmillerc/cat-facts-operator/api/v1beta1"
+)
+
+func TestRingBalance(t *testing.T) {
+	ring := NewRing([]string{"a", "b", "c"})
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		counts[ring.Owner(Key("default", fmt.Sprintf("cat-%d", i)))]++
+	}
+	for _, member := range []string{"a", "b", "c"} {
+		if counts[member] < 700 || counts[member] > 1300 {
+			t.Errorf("Expected about 1000 CatFacts owned by %s, got %v", member, counts)
+		}
+	}
+
+	if owner := NewRing(nil).Owner("default/cat"); owner != "" {
+		t.Errorf("Expected no owner on an empty ring, got %s", owner)
+	}
+}
+
+func TestRingStability(t *testing.T) {
+	before := NewRing([]string{"a", "b", "c"})
+	after := NewRing([]string{"c", "a", "b", "d"})
+	moved := 0
+	for i := 0; i < 3000; i++ {
+		key := Key("default", fmt.Sprintf("cat-%d", i))
+		if from, to := before.Owner(key), after.Owner(key); from != to {
+			if to != "d" {
+				t.Fatalf("Expected %s to only move to the new member, moved from %s to %s", key, from, to)
+			}
+			moved++
+		}
+	}
+	// About a quarter of CatFacts should move to the new member
+	if moved < 450 || moved > 1050 {
+		t.Errorf("Expected about 750 CatFacts to move, got %d", moved)
+	}
+}
+
+func newLease(identity string, renewed time.Time) coordinationv1.Lease {
+	return coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      MemberLeasePrefix + identity,
+			Namespace: "cat-facts-operator",
+			Labels:    map[string]string{MemberLeaseLabel: MemberLeaseGroup},
+		},
+		Spec: coordinationv1.LeaseSpec{
+			HolderIdentity:       ptr.To(identity),
+			LeaseDurationSeconds: ptr.To(int32(15)),
+			RenewTime:            &metav1.MicroTime{Time: renewed},
+		},
+	}
+}
+
+func TestLiveMembers(t *testing.T) {
+	now := time.Now()
+	leases := []coordinationv1.Lease{
+		newLease("live", now.Add(-5*time.Second)),
+		newLease("expired", now.Add(-time.Minute)),
+		{ObjectMeta: metav1.ObjectMeta{Name: "empty"}},
+	}
+	if members := LiveMembers(leases, now); len(members) != 1 || members[0] != "live" {
+		t.Errorf("Expected only the live member, got %v", members)
+	}
+}
+
+func newScheme() *runtime.Scheme {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = tacomoev1beta1.AddToScheme(scheme)
+	return scheme
+}
+
+func TestMember(t *testing.T) {
+	kclient := fake.NewClientBuilder().WithScheme(newScheme()).Build()
+	member := &Member{Client: kclient, Namespace: "cat-facts-operator", Identity: "pod-a", RenewInterval: time.Millisecond}
+	key := client.ObjectKey{Namespace: "cat-facts-operator", Name: MemberLeasePrefix + "pod-a"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- member.Start(ctx) }()
+
+	lease := &coordinationv1.Lease{}
+	deadline := time.Now().Add(5 * time.Second)
+	for kclient.Get(ctx, key, lease) != nil && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if members := LiveMembers([]coordinationv1.Lease{*lease}, time.Now()); len(members) != 1 || members[0] != "pod-a" {
+		t.Fatalf("Expected pod-a to hold a live lease, got %+v", lease)
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := kclient.Get(context.Background(), key, lease); !kerrors.IsNotFound(err) {
+		t.Errorf("Expected the lease to be deleted when the member stops, got %v", err)
+	}
+}
+
+// Cache backed by a client, for the Get and List calls the Sharder makes
+type fakeCache struct {
+	cache.Cache
+	reader client.Reader
+}
+
+func (c *fakeCache) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	return c.reader.Get(ctx, key, obj, opts...)
+}
+
+func (c *fakeCache) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	return c.reader.List(ctx, list, opts...)
+}
+
+func TestSharder(t *testing.T) {
+	now := time.Now()
+	leaseA, leaseB := newLease("pod-a", now), newLease("pod-b", now)
+	var objects []client.Object
+	for i := 0; i < 20; i++ {
+		objects = append(objects, &tacomoev1beta1.CatFact{
+			ObjectMeta: metav1.ObjectMeta{Name: fmt.Sprintf("cat-%d", i), Namespace: "default"},
+		})
+	}
+	kclient := fake.NewClientBuilder().WithScheme(newScheme()).WithObjects(objects...).WithObjects(&leaseA).Build()
+	sharder := &Sharder{Client: kclient, Cache: &fakeCache{reader: kclient}, Namespace: "cat-facts-operator"}
+	ctx := context.Background()
+
+	reconcileAll := func(requests []ctrl.Request) map[string]string {
+		t.Helper()
+		for _, req := range requests {
+			if _, err := sharder.Reconcile(ctx, req); err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+		}
+		owners := map[string]string{}
+		var catFacts tacomoev1beta1.CatFactList
+		if err := kclient.List(ctx, &catFacts); err != nil {
+			t.Fatal(err)
+		}
+		for _, catFact := range catFacts.Items {
+			owners[catFact.Name] = catFact.Labels[ShardLabel]
+		}
+		return owners
+	}
+
+	requests := sharder.rebalance(ctx, &leaseA)
+	if len(requests) != 20 {
+		t.Fatalf("Expected every CatFact to be requeued when the first member joins, got %d", len(requests))
+	}
+	for name, owner := range reconcileAll(requests) {
+		if owner != "pod-a" {
+			t.Errorf("Expected %s to be owned by pod-a, got %q", name, owner)
+		}
+	}
+
+	if requests := sharder.rebalance(ctx, &leaseA); len(requests) != 0 {
+		t.Errorf("Expected nothing to be requeued when members don't change, got %d", len(requests))
+	}
+
+	if err := kclient.Create(ctx, &leaseB); err != nil {
+		t.Fatal(err)
+	}
+	owners := reconcileAll(sharder.rebalance(ctx, &leaseB))
+	ring := NewRing([]string{"pod-a", "pod-b"})
+	for name, owner := range owners {
+		if want := ring.Owner(Key("default", name)); owner != want {
+			t.Errorf("Expected %s to be owned by %s, got %q", name, want, owner)
+		}
+	}
+
+	if _, err := sharder.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "missing"}}); err != nil {
+		t.Errorf("Expected a deleted CatFact to be ignored, got %v", err)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+OpenTelemetry tracing for the Cat Facts Operator.
+
+Spans are created for every CatFact reconcile, for ProcessCatFact, for fact
+providers, and for requests to fact APIs. Trace context is propagated to fact
+APIs with W3C Trace Context headers.
+
+Tracing is a no-op unless an OTLP endpoint is configured, either with Setup or
+the standard OTEL_EXPORTER_OTLP_ENDPOINT environment variables.
+*/
+
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"os"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+var tracingLog = ctrl.Log.WithName("tracing")
+
+// Name of the tracer, and the service name reported to the collector
+const (
+	TracerName  = "github.com/ryanmillerc/cat-facts-operator"
+	ServiceName = "cat-facts-operator"
+)
+
+// Environment variables read by the OTLP exporter. Tracing is enabled if
+// either is set, even without an endpoint passed to Setup.
+var endpointEnvVars = []string{"OTEL_EXPORTER_OTLP_ENDPOINT", "OTEL_EXPORTER_OTLP_TRACES_ENDPOINT"}
+
+// Set the global propagator and, if an endpoint is configured, a tracer
+// provider that exports spans over OTLP/HTTP. endpoint is a URL such as
+// http://otel-collector:4318. A fraction sampleRatio of new traces is
+// sampled. Call the returned function to flush spans on shutdown.
+func Setup(ctx context.Context, endpoint string, sampleRatio float64) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
+
+	if endpoint == "" && !endpointFromEnv() {
+		tracingLog.Info("tracing disabled, no OTLP endpoint configured")
+		return func(context.Context) error { return nil }, nil
+	}
+
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
+	exporter, err := otlptracehttp.New(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := NewTracerProvider(exporter, sampleRatio)
+	otel.SetTracerProvider(provider)
+	tracingLog.Info("tracing enabled", "endpoint", endpoint, "sampleRatio", sampleRatio)
+	return provider.Shutdown, nil
+}
+
+// Return a tracer provider that batches spans to exporter. Tests pass a
+// tracetest.InMemoryExporter.
+func NewTracerProvider(exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(ServiceName))),
+	)
+}
+
+func endpointFromEnv() bool {
+	for _, name := range endpointEnvVars {
+		if os.Getenv(name) != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Return the operator's tracer from the global tracer provider
+func Tracer() trace.Tracer {
+	return otel.Tracer(TracerName)
+}
+
+// Start a span with the operator's tracer
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return Tracer().Start(ctx, name, opts...)
+}
+
+// Record err on span, if it's not nil, and mark the span as failed
+func RecordError(span trace.Span, err error) {
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// Return the ID of the trace in ctx, or "" if ctx isn't part of a sampled
+// trace
+func TraceID(ctx context.Context) string {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsSampled() {
+		return ""
+	}
+	return spanContext.TraceID().String()
+}
+
+// Wrap an HTTP transport so requests get a client span and carry the trace
+// context to the server. A nil base uses http.DefaultTransport.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return otelhttp.NewTransport(base)
+}
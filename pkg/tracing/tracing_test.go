@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSetupDisabled(t *testing.T) {
+	t.Setenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")
+	t.Setenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", "")
+	shutdown, err := Setup(context.Background(), "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer shutdown(context.Background())
+
+	ctx, span := Start(context.Background(), "test")
+	defer span.End()
+	if span.IsRecording() {
+		t.Errorf("Expected spans not to be recorded without an endpoint")
+	}
+	if id := TraceID(ctx); id != "" {
+		t.Errorf("Expected no trace ID, got %s", id)
+	}
+}
+
+func TestTraceID(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	provider := NewTracerProvider(exporter, 1)
+	defer provider.Shutdown(context.Background())
+
+	ctx, span := provider.Tracer(TracerName).Start(context.Background(), "test")
+	span.End()
+	if id := TraceID(ctx); id != span.SpanContext().TraceID().String() {
+		t.Errorf("Expected trace ID %s, got %q", span.SpanContext().TraceID(), id)
+	}
+
+	// Unsampled traces aren't exported, so their IDs aren't useful in logs
+	unsampled := NewTracerProvider(exporter, 0)
+	defer unsampled.Shutdown(context.Background())
+	ctx, span = unsampled.Tracer(TracerName).Start(context.Background(), "test")
+	span.End()
+	if id := TraceID(ctx); id != "" {
+		t.Errorf("Expected no trace ID for an unsampled trace, got %s", id)
+	}
+}
+
+func TestSetupExportsSpans(t *testing.T) {
+	received := make(chan string, 1)
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case received <- r.URL.Path:
+		default:
+		}
+	}))
+	defer collector.Close()
+
+	shutdown, err := Setup(context.Background(), collector.URL+"/v1/traces", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, span := Start(context.Background(), "test")
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case path := <-received:
+		if path != "/v1/traces" {
+			t.Errorf("Expected spans to be sent to /v1/traces, got %s", path)
+		}
+	default:
+		t.Errorf("Expected spans to be exported to the collector")
+	}
+}